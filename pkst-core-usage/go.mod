@@ -2,4 +2,6 @@ module github.com/nailec/ps-usage-stats/pkst-core-usage
 
 go 1.14
 
-require github.com/pkg/errors v0.9.1
+require github.com/nailec/ps-usage-stats/pokedex v0.0.0
+
+replace github.com/nailec/ps-usage-stats/pokedex => ../pokedex
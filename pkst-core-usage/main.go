@@ -3,14 +3,13 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
 
-	"github.com/pkg/errors"
+	"github.com/nailec/ps-usage-stats/pokedex"
 )
 
 var ExpectedColumns int = 59
@@ -20,6 +19,8 @@ var LeadIndex int = 2
 var PokemonsStart int = 4
 var PokemonsColumns int = 9
 
+var dex = pokedex.Load() // Species data of the generation of the format
+
 type CoreStats struct {
 	nbUsed         int
 	nbWins         int
@@ -85,6 +86,8 @@ type PokeInfo struct {
 	Name    string         `json:"name"`
 	PV      *int           `json:"pv"`
 	Attacks map[string]int `json:"attacks"`
+
+	hidden bool // Forme hidden by team preview (Silvally-*)
 }
 
 func (g *Game) String() string {
@@ -107,6 +110,7 @@ func main() {
 	}
 
 	format := args[1]
+	dex = pokedex.ForGen(pokedex.FormatGen(format))
 	count, err := strconv.Atoi(args[2])
 	if err != nil {
 		fmt.Println("cannot parse argument: ", err)
@@ -285,25 +289,30 @@ func (g *Game) RemoveDuplicates() {
 	g.PokemonPlayerTwo = removeDuplicates(g.PokemonPlayerTwo)
 }
 
+// removeDuplicates keeps a pokemon per species, the pokemons listed with
+// their forme changes (Charizard and Charizard-Mega-X) are counted once under
+// the last name in alphabetical order
 func removeDuplicates(pis []*PokeInfo) []*PokeInfo {
-	res := make([]*PokeInfo, len(pis))
-	i := 0
+	res := make([]*PokeInfo, 0, len(pis))
 	sort.SliceStable(pis, func(i, j int) bool {
 		return pis[i].Name < pis[j].Name
 	})
 
-	for j, pi := range pis {
-		pi.Name = cutName(pi.Name)
-		if j != 0 && strings.Split(pis[j-1].Name, "-")[0] == strings.Split(pi.Name, "-")[0] {
-			res[i-1] = pi
+	seen := map[string]int{}
+	for _, pi := range pis {
+		pi.hidden = strings.HasSuffix(pi.Name, "-*")
+		pi.Name = dex.Normalize(pi.Name)
+		species := dex.BaseSpecies(pi.Name)
+		if i, ok := seen[species]; ok {
+			res[i] = pi
 			continue
 		}
 
-		res[i] = pi
-		i++
+		seen[species] = len(res)
+		res = append(res, pi)
 	}
 
-	return res[:i]
+	return res
 }
 
 func (f *TeamFilter) matchTeamWithout(player string, pi []*PokeInfo) bool {
@@ -317,13 +326,13 @@ func (f *TeamFilter) matchTeamWithout(player string, pi []*PokeInfo) bool {
 	//	}
 
 	if len(f.Type) != 0 {
-		pType, err := GetType(pi)
+		pTypes, err := GetTypes(pi)
 		if err != nil {
 			fmt.Println(err)
 			return false
 		}
 
-		if stringInSlice(pType, f.Type) {
+		if anyInSlice(pTypes, f.Type) {
 			return true
 		}
 	}
@@ -345,13 +354,13 @@ func (f *TeamFilter) matchTeam(player string, pi []*PokeInfo) bool {
 	//	}
 
 	if len(f.Type) != 0 {
-		pType, err := GetType(pi)
+		pTypes, err := GetTypes(pi)
 		if err != nil {
 			fmt.Println(err)
 			return false
 		}
 
-		if !stringInSlice(pType, f.Type) {
+		if !anyInSlice(pTypes, f.Type) {
 			return false
 		}
 	}
@@ -411,6 +420,17 @@ func stringInSlice(p1 string, ps []string) bool {
 	return false
 }
 
+// anyInSlice returns whether one of ps1 is in ps2
+func anyInSlice(ps1, ps2 []string) bool {
+	for _, p1 := range ps1 {
+		if stringInSlice(p1, ps2) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func stringInSliceInsensitive(p1 string, ps []string) bool {
 	for _, p2 := range ps {
 		if strings.ToLower(p1) == strings.ToLower(p2) {
@@ -535,164 +555,18 @@ bloop:
 	return true
 }
 
-func cutName(name string) string {
-	if strings.HasPrefix(name, "Urshifu") {
-		return "Urshifu" // TODO fixme
-	}
-
-	if strings.HasPrefix(name, "Mimikyu") {
-		return "Mimikyu"
-	}
-
-	if strings.HasPrefix(name, "Minior") {
-		return "Minior"
-	}
-
-	//	if strings.HasPrefix(name, "Pumpkaboo") {
-	//		return "Pumpkaboo"
-	//	}
-	//
-	//	if strings.HasPrefix(name, "Gourgeist") {
-	//		return "Gourgeist"
-	//	}
-
-	if strings.HasPrefix(name, "Toxtricity") {
-		return "Toxtricity"
-	}
-
-	if strings.HasPrefix(name, "Genesect") {
-		return "Genesect"
-	}
-
-	if strings.HasPrefix(name, "Eiscue") {
-		return "Eiscue"
-	}
-
-	if strings.HasPrefix(name, "Sawsbuck") {
-		return "Sawsbuck"
-	}
-
-	if strings.HasPrefix(name, "Deerling") {
-		return "Deerling"
-	}
-
-	if strings.HasPrefix(name, "Alcremie") {
-		return "Alcremie"
-	}
-
-	if strings.HasPrefix(name, "Pikachu") {
-		return "Pikachu"
-	}
-
-	if strings.HasPrefix(name, "Vivillon") {
-		return "Vivillon"
-	}
-
-	if strings.HasPrefix(name, "Florges") {
-		return "Florges"
-	}
-
-	if strings.HasPrefix(name, "Flabebe") {
-		return "Flabebe"
-	}
-
-	if strings.HasPrefix(name, "Floette") {
-		return "Floette"
-	}
-
-	if strings.HasPrefix(name, "Furfrou") {
-		return "Furfrou"
-	}
-
-	if name == "Gastrodon-East" {
-		return "Gastrodon"
-	}
-
-	if name == "Shellos-East" {
-		return "Shellos"
-	}
-
-	if name == "Basculin-Blue-Striped" {
-		return "Basculin"
-	}
-
-	if name == "Polteageist-Antique" {
-		return "Polteageist"
-	}
-
-	if name == "Keldeo-Resolute" {
-		return "Keldeo"
-	}
-
-	if strings.HasSuffix(name, "-Totem") {
-		return strings.TrimSuffix(name, "-Totem")
-	}
-
-	return name
-}
-
-type pokeList struct {
-	Data []string
-}
-
-func GetType(team []*PokeInfo) (string, error) {
-	types := []string{"bug", "dark", "dragon", "electric",
-		"fairy", "fighting", "fire", "flying", "ghost",
-		"grass", "ground", "ice", "normal", "poison",
-		"psychic", "rock", "steel", "water",
-	}
-
-	var names []string
-	for _, t := range types {
-		names = make([]string, len(team))
-		i := 0
-
-		b, err := ioutil.ReadFile("pokelist/" + t + ".json")
-		if err != nil {
-			return "", errors.Wrap(err, "could not read type file: "+t)
-		}
-
-		var list pokeList
-		err = json.Unmarshal(b, &list)
-		if err != nil {
-			return "", errors.Wrap(err, "could not unmarshal poke list of type: "+t)
-		}
-
-		typeMatch := true
-		for j, p := range team {
-			if p.Name == "" || j != 0 && team[j-1].Name == p.Name {
-				continue
-			}
-			p.Name = cutName(p.Name)
-			names[i] = p.Name
-			i++
-			if strings.HasPrefix(p.Name, "Silvally-") {
-				continue
-			}
-			if strings.HasSuffix(p.Name, "-*") {
-				continue
-			}
-			found := false
-			for _, p2 := range list.Data {
-				if p.Name == p2 {
-					found = true
-					break
-				}
-			}
-
-			if !found {
-				typeMatch = false
-			}
-
-			if i >= len(team) {
-				break
-			}
-		}
-
-		if typeMatch {
-			return t, nil
+// GetTypes returns the types shared by all the pokemons of the team
+func GetTypes(team []*PokeInfo) ([]string, error) {
+	names := make([]string, len(team))
+	for i, p := range team {
+		// An unrevealed Silvally-* can have the type of any of its formes
+		if p.hidden {
+			names[i] = p.Name + "-*"
+		} else {
+			names[i] = dex.Normalize(p.Name)
 		}
 	}
 
-	return "Unknown", fmt.Errorf("no type found for team: %+v", names)
+	_, shared, err := dex.Monotype(names)
+	return shared, err
 }
@@ -0,0 +1,3 @@
+module github.com/nailec/ps-usage-stats/pokedex
+
+go 1.16
@@ -0,0 +1,431 @@
+// Package pokedex normalises the species names found in replays and usage
+// data with a showdown style pokedex bundled in pokedex.json, it is shared by
+// all the tools so they count the same pokemons
+package pokedex
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Latest is the generation of pokedex.json
+const Latest = 9
+
+//go:embed pokedex.json
+var data []byte
+
+//go:embed typechart.json
+var chartData []byte
+
+// The changes of each older generation, as showdown's data/mods
+//
+//go:embed mods/*/*.json
+var mods embed.FS
+
+// Last national dex number of each generation
+var lastNums = []int{151, 251, 386, 493, 649, 721, 809, 905, 1025}
+
+// Species is an entry of pokedex.json, keyed by ID as in showdown's data
+type Species struct {
+	Num         int      `json:"num"`
+	Name        string   `json:"name"`
+	BaseSpecies string   `json:"baseSpecies"` // "" for a base species
+	Forme       string   `json:"forme"`
+	Types       []string `json:"types"`
+	Gen         int      `json:"gen"` // Only set on the formes introduced after their species
+
+	// Formes counted as the species: cosmetic ones and the ones which only
+	// differ by an item or a move (Genesect-Douse, Keldeo-Resolute...)
+	CosmeticFormes []string `json:"cosmeticFormes"`
+	BattleOnly     string   `json:"battleOnly"`  // Forme it takes in battle from (Mimikyu-Busted, Charizard-Mega-X...)
+	ChangesFrom    string   `json:"changesFrom"` // Gigantamax and totem formes
+}
+
+// Generation returns the generation the species or forme was introduced in
+func (s *Species) Generation() int {
+	if s.Gen != 0 {
+		return s.Gen
+	}
+
+	for i, last := range lastNums {
+		if s.Num <= last {
+			return i + 1
+		}
+	}
+	return Latest
+}
+
+// Dex is the species data
+type Dex struct {
+	species  map[string]*Species
+	cosmetic map[string]*Species   // Species by ID of their cosmetic formes
+	formes   map[string][]*Species // Formes by ID of their base species
+	chart    TypeChart
+	types    []string // Types of the generation, sorted
+}
+
+var (
+	loadOnce sync.Once
+	bundled  *Dex
+
+	gensMu sync.Mutex
+	gens   = map[int]*Dex{} // Bundled dex of the older generations
+)
+
+// Load returns the bundled dex, read once
+func Load() *Dex {
+	loadOnce.Do(func() {
+		var err error
+		bundled, err = Parse(data, chartData)
+		if err != nil {
+			panic("pokedex: invalid bundled pokedex.json: " + err.Error())
+		}
+	})
+
+	return bundled
+}
+
+// Parse reads a pokedex and a type chart in the format of pokedex.json and
+// typechart.json
+func Parse(dex, chart []byte) (*Dex, error) {
+	species := map[string]*Species{}
+	err := json.Unmarshal(dex, &species)
+	if err != nil {
+		return nil, err
+	}
+
+	tc := TypeChart{}
+	err = json.Unmarshal(chart, &tc)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDex(species, tc), nil
+}
+
+func newDex(species map[string]*Species, chart TypeChart) *Dex {
+	d := &Dex{
+		species:  species,
+		cosmetic: map[string]*Species{},
+		formes:   map[string][]*Species{},
+		chart:    chart,
+	}
+
+	seen := map[string]bool{}
+	for _, s := range d.species {
+		for _, t := range s.Types {
+			if !seen[t] {
+				seen[t] = true
+				d.types = append(d.types, t)
+			}
+		}
+
+		for _, forme := range s.CosmeticFormes {
+			d.cosmetic[ToID(forme)] = s
+		}
+		base := ToID(d.baseName(s))
+		d.formes[base] = append(d.formes[base], s)
+	}
+
+	sort.Strings(d.types)
+
+	// The base species first
+	for _, formes := range d.formes {
+		sort.Slice(formes, func(i, j int) bool {
+			if (formes[i].BaseSpecies == "") != (formes[j].BaseSpecies == "") {
+				return formes[i].BaseSpecies == ""
+			}
+			return formes[i].Name < formes[j].Name
+		})
+	}
+
+	return d
+}
+
+// ForGen returns the bundled dex as it was in this generation, the latest one
+// if gen is 0
+func ForGen(gen int) *Dex {
+	if gen <= 0 || gen >= Latest {
+		return Load()
+	}
+
+	gensMu.Lock()
+	defer gensMu.Unlock()
+	if d, ok := gens[gen]; ok {
+		return d
+	}
+
+	d, err := Load().forGen(gen)
+	if err != nil {
+		panic("pokedex: invalid bundled mods: " + err.Error())
+	}
+	gens[gen] = d
+
+	return d
+}
+
+// forGen drops the species introduced after gen and restores the typings and
+// type matchups changed since then, the mods are applied from the latest
+// generation down (Clefairy is Normal from gen 5 down, Magnemite is Electric
+// in gen 1)
+func (d *Dex) forGen(gen int) (*Dex, error) {
+	species := map[string]*Species{}
+	for id, s := range d.species {
+		if s.Generation() <= gen {
+			c := *s
+			species[id] = &c
+		}
+	}
+
+	chart := TypeChart{}
+	for t, matchups := range d.chart {
+		chart[t] = matchups
+	}
+
+	for g := Latest - 1; g >= gen; g-- {
+		var changes map[string]*Species
+		err := readMod(g, "pokedex", &changes)
+		if err != nil {
+			return nil, err
+		}
+		for id, change := range changes {
+			if s, ok := species[id]; ok && len(change.Types) != 0 {
+				s.Types = change.Types
+			}
+		}
+
+		var matchups TypeChart
+		err = readMod(g, "typechart", &matchups)
+		if err != nil {
+			return nil, err
+		}
+		for t, m := range matchups {
+			chart[t] = m
+		}
+	}
+
+	return newDex(species, chart), nil
+}
+
+// readMod reads mods/genN/name.json into v, v is left as is if the
+// generation has no such changes
+func readMod(gen int, name string, v interface{}) error {
+	b, err := mods.ReadFile("mods/gen" + strconv.Itoa(gen) + "/" + name + ".json")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(b, v)
+	if err != nil {
+		return fmt.Errorf("gen%d %s: %v", gen, name, err)
+	}
+	return nil
+}
+
+var formatGen = regexp.MustCompile(`^gen(\d+)`)
+
+// FormatGen returns the generation of a format ID (gen7lc), the latest one if
+// the format does not tell
+func FormatGen(format string) int {
+	res := formatGen.FindStringSubmatch(strings.ToLower(format))
+	if len(res) == 0 {
+		return Latest
+	}
+
+	gen, err := strconv.Atoi(res[1])
+	if err != nil || gen < 1 {
+		return Latest
+	}
+	return gen
+}
+
+// Get returns the species with this name, a cosmetic forme returns its species
+func (d *Dex) Get(name string) (*Species, bool) {
+	id := ToID(name)
+	if s, ok := d.species[id]; ok {
+		return s, true
+	}
+
+	s, ok := d.cosmetic[id]
+	return s, ok
+}
+
+// Normalize returns the name under which the pokemon is counted: cosmetic,
+// gigantamax, totem and temporary battle formes are the species (megas and
+// primals are kept). Hidden formes of team preview (Urshifu-*) are the base
+// species until revealed and unknown names are returned as is
+func (d *Dex) Normalize(name string) string {
+	if strings.HasSuffix(name, "-*") {
+		return d.BaseSpecies(name)
+	}
+
+	s, ok := d.Get(name)
+	if !ok {
+		return name
+	}
+
+	if s.ChangesFrom != "" {
+		return d.Normalize(s.ChangesFrom)
+	}
+
+	if s.BattleOnly != "" && !strings.HasPrefix(s.Forme, "Mega") && s.Forme != "Primal" {
+		return s.BattleOnly
+	}
+
+	return s.Name
+}
+
+// BaseSpecies returns the species of a forme: Rotom for Rotom-Wash, Silvally
+// for Silvally-*. Unknown names are returned as is
+func (d *Dex) BaseSpecies(name string) string {
+	name = strings.TrimSuffix(name, "-*")
+	s, ok := d.Get(name)
+	if !ok {
+		return name
+	}
+
+	return d.baseName(s)
+}
+
+func (d *Dex) baseName(s *Species) string {
+	if s.BaseSpecies != "" {
+		return s.BaseSpecies
+	}
+	return s.Name
+}
+
+// SameSpecies returns whether the names are formes of the same species,
+// Porygon and Porygon-Z or Hakamo-o and Kommo-o are not
+func (d *Dex) SameSpecies(a, b string) bool {
+	return a == b || d.BaseSpecies(a) == d.BaseSpecies(b)
+}
+
+// Types returns the types the pokemon can have, the primary type first. A
+// forme hidden by team preview (Silvally-*) can have the types of all the
+// formes of its species
+func (d *Dex) Types(name string) ([]string, bool) {
+	if !strings.HasSuffix(name, "-*") {
+		s, ok := d.Get(name)
+		if !ok {
+			return nil, false
+		}
+		return s.Types, true
+	}
+
+	formes := d.formes[ToID(d.BaseSpecies(name))]
+	if len(formes) == 0 {
+		return nil, false
+	}
+
+	var types []string
+	seen := map[string]bool{}
+	for _, s := range formes {
+		for _, t := range s.Types {
+			if !seen[t] {
+				seen[t] = true
+				types = append(types, t)
+			}
+		}
+	}
+
+	return types, true
+}
+
+// Monotype returns the types shared by all the pokemons of the team, in
+// lowercase as in the format names, and the declared type of the team: the
+// shared type which is the primary type of the most pokemons, the first in
+// alphabetical order on a tie (Water for Swampert, Gastrodon and Pelipper).
+// Empty names are skipped
+func (d *Dex) Monotype(names []string) (string, []string, error) {
+	counts := map[string]int{}    // Pokemons having the type
+	primaries := map[string]int{} // Pokemons having it as primary type
+	n := 0
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+
+		types, ok := d.Types(name)
+		if !ok {
+			return "", nil, fmt.Errorf("unknown pokemon %s in team: %+v", name, names)
+		}
+
+		n++
+		primaries[types[0]]++
+		for _, t := range types {
+			counts[t]++
+		}
+	}
+
+	var shared []string
+	for t, count := range counts {
+		if count == n {
+			shared = append(shared, t)
+		}
+	}
+	if len(shared) == 0 {
+		return "", nil, fmt.Errorf("no type found for team: %+v", names)
+	}
+
+	sort.Strings(shared)
+	declared := shared[0]
+	for _, t := range shared {
+		if primaries[t] > primaries[declared] {
+			declared = t
+		}
+	}
+
+	for i := range shared {
+		shared[i] = strings.ToLower(shared[i])
+	}
+
+	return strings.ToLower(declared), shared, nil
+}
+
+// Normalize normalises the name with the bundled dex, see Dex.Normalize
+func Normalize(name string) string {
+	return Load().Normalize(name)
+}
+
+// BaseSpecies returns the species of a forme with the bundled dex
+func BaseSpecies(name string) string {
+	return Load().BaseSpecies(name)
+}
+
+// SameSpecies compares the species of the names with the bundled dex
+func SameSpecies(a, b string) bool {
+	return Load().SameSpecies(a, b)
+}
+
+// Monotype returns the declared and shared types of the team with the bundled
+// dex, see Dex.Monotype
+func Monotype(names []string) (string, []string, error) {
+	return Load().Monotype(names)
+}
+
+// ToID returns the ID of a name as showdown does: Flabébé -> flabebe,
+// Farfetch’d -> farfetchd, Kommo-o -> kommoo
+func ToID(name string) string {
+	id := make([]byte, 0, len(name))
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r == 'é':
+			id = append(id, 'e')
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			id = append(id, byte(r))
+		}
+	}
+
+	return string(id)
+}
@@ -0,0 +1,196 @@
+package pokedex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"Gastrodon-East":       "Gastrodon",
+		"Genesect-Douse":       "Genesect",
+		"Keldeo-Resolute":      "Keldeo",
+		"Pikachu-Original":     "Pikachu",
+		"Venusaur-Gmax":        "Venusaur",
+		"Mimikyu-Busted":       "Mimikyu",
+		"Darmanitan-Galar-Zen": "Darmanitan-Galar",
+		"Charizard-Mega-X":     "Charizard-Mega-X",
+		"Rotom-Wash":           "Rotom-Wash",
+		"Urshifu-*":            "Urshifu",
+		"Silvally-*":           "Silvally",
+		"Silvally-Fire":        "Silvally-Fire",
+		"flabebe":              "Flabébé",
+		"Missingno-Glitch":     "Missingno-Glitch",
+	}
+	for name, want := range tests {
+		if got := Normalize(name); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBaseSpecies(t *testing.T) {
+	tests := map[string]string{
+		"Rotom-Wash":           "Rotom",
+		"Silvally-*":           "Silvally",
+		"Urshifu-Rapid-Strike": "Urshifu",
+		"Kommo-o":              "Kommo-o",
+		"Porygon-Z":            "Porygon-Z",
+		"Missingno":            "Missingno",
+	}
+	for name, want := range tests {
+		if got := BaseSpecies(name); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSameSpecies(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Rotom", "Rotom-Wash", true},
+		{"Urshifu-*", "Urshifu-Rapid-Strike", true},
+		{"Porygon", "Porygon-Z", false},
+		{"Hakamo-o", "Kommo-o", false},
+		{"Tapu Koko", "Tapu Lele", false},
+	}
+	for _, tt := range tests {
+		if got := SameSpecies(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s, %s: got %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestToID(t *testing.T) {
+	tests := map[string]string{
+		"Flabébé":          "flabebe",
+		"Farfetch’d":       "farfetchd",
+		"Kommo-o":          "kommoo",
+		"Mr. Mime":         "mrmime",
+		"Charizard-Mega-X": "charizardmegax",
+	}
+	for name, want := range tests {
+		if got := ToID(name); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	dex := []byte(`{"shellos": {"num": 422, "name": "Shellos", "types": ["Water"], "cosmeticFormes": ["Shellos-East"]}}`)
+	chart := []byte(`{"Water": {"Electric": 1, "Fire": 2}}`)
+	d, err := Parse(dex, chart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Normalize("Shellos-East"); got != "Shellos" {
+		t.Errorf("got %q, want Shellos", got)
+	}
+	if got := d.Effectiveness("Electric", []string{"Water"}); got != 2 {
+		t.Errorf("got %v, want 2", got)
+	}
+
+	if _, err := Parse([]byte(`[]`), chart); err == nil {
+		t.Error("got no error for an invalid pokedex")
+	}
+	if _, err := Parse(dex, []byte(`[]`)); err == nil {
+		t.Error("got no error for an invalid type chart")
+	}
+}
+
+func TestTypes(t *testing.T) {
+	tests := map[string][]string{
+		"Rotom-Wash": {"Electric", "Water"},
+		"Silvally":   {"Normal"},
+		"Urshifu-*":  {"Fighting", "Dark", "Water"},
+	}
+	for name, want := range tests {
+		if got, ok := Load().Types(name); !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+
+	if types, _ := Load().Types("Silvally-*"); len(types) != 18 {
+		t.Errorf("Silvally-*: got %q, want the 18 types", types)
+	}
+	if _, ok := Load().Types("Missingno"); ok {
+		t.Error("Missingno: got types")
+	}
+}
+
+func TestMonotype(t *testing.T) {
+	tests := []struct {
+		names    []string
+		declared string
+		shared   []string
+	}{
+		{[]string{"Swampert", "Gastrodon", "Quagsire"}, "water", []string{"ground", "water"}},
+		{[]string{"Heatran", "Silvally-*", "Volcarona", ""}, "fire", []string{"fire"}},
+		{[]string{"Rotom-Wash", "Pelipper"}, "water", []string{"water"}},
+	}
+	for _, tt := range tests {
+		declared, shared, err := Monotype(tt.names)
+		if err != nil || declared != tt.declared || !reflect.DeepEqual(shared, tt.shared) {
+			t.Errorf("%q: got %s %q, %v, want %s %q", tt.names, declared, shared, err, tt.declared, tt.shared)
+		}
+	}
+
+	for _, names := range [][]string{{"Swampert", "Zapdos"}, {"Swampert", "Missingno"}} {
+		if _, _, err := Monotype(names); err == nil {
+			t.Errorf("%q: got no error", names)
+		}
+	}
+}
+
+func TestForGen(t *testing.T) {
+	tests := []struct {
+		gen  int
+		name string
+		want []string
+	}{
+		{5, "Clefable", []string{"Normal"}},
+		{6, "Clefable", []string{"Fairy"}},
+		{4, "Rotom-Wash", []string{"Electric", "Ghost"}},
+		{5, "Rotom-Wash", []string{"Electric", "Water"}},
+		{1, "Magneton", []string{"Electric"}},
+		{2, "Magneton", []string{"Electric", "Steel"}},
+		{0, "Clefable", []string{"Fairy"}},
+	}
+	for _, tt := range tests {
+		if got, ok := ForGen(tt.gen).Types(tt.name); !ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("gen %d %s: got %q, want %q", tt.gen, tt.name, got, tt.want)
+		}
+	}
+
+	// The species and formes introduced later are unknown
+	for _, name := range []string{"Sylveon", "Charizard-Mega-X"} {
+		if _, ok := ForGen(5).Get(name); ok {
+			t.Errorf("gen 5: got %s", name)
+		}
+	}
+	if _, ok := ForGen(6).Get("Charizard-Mega-X"); !ok {
+		t.Error("gen 6: Charizard-Mega-X is missing")
+	}
+
+	// The bundled dex is not modified
+	if got, _ := Load().Types("Clefable"); !reflect.DeepEqual(got, []string{"Fairy"}) {
+		t.Errorf("got %q, want Fairy", got)
+	}
+}
+
+func TestFormatGen(t *testing.T) {
+	tests := map[string]int{
+		"gen7lc":          7,
+		"Gen4OU":          4,
+		"gen9vgc2024regg": 9,
+		"ou":              Latest,
+		"gen0ou":          Latest,
+	}
+	for format, want := range tests {
+		if got := FormatGen(format); got != want {
+			t.Errorf("%s: got %d, want %d", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package pokedex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Damage taken codes of typechart.json, as in showdown's data/typechart
+const (
+	Neutral = iota
+	Weak
+	Resist
+	Immune
+)
+
+// TypeChart holds the damage taken by each defending type, by attacking type.
+// The neutral matchups are left out
+type TypeChart map[string]map[string]int
+
+// Effectiveness returns the damage multiplier of an attack on these types
+func (d *Dex) Effectiveness(attack string, types []string) float64 {
+	res := 1.0
+	for _, t := range types {
+		switch d.chart[t][attack] {
+		case Weak:
+			res *= 2
+		case Resist:
+			res /= 2
+		case Immune:
+			return 0
+		}
+	}
+
+	return res
+}
+
+// Profile is the type profile of a team, the counts are numbers of pokemons.
+// Abilities (Levitate) and tera types are not taken into account
+type Profile struct {
+	Types       map[string]int `json:"types"`
+	Weaknesses  map[string]int `json:"weaknesses"`  // By attacking type
+	Resistances map[string]int `json:"resistances"` // Immunities excluded
+	Immunities  map[string]int `json:"immunities"`
+}
+
+// Profile returns the type profile of the team, the forme hidden by team
+// preview (Silvally-*) counts as its base species. Empty names are skipped
+func (d *Dex) Profile(names []string) (*Profile, error) {
+	p := &Profile{
+		Types:       map[string]int{},
+		Weaknesses:  map[string]int{},
+		Resistances: map[string]int{},
+		Immunities:  map[string]int{},
+	}
+
+	var unknown []string
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+
+		s, ok := d.Get(strings.TrimSuffix(name, "-*"))
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+
+		for _, t := range s.Types {
+			p.Types[t]++
+		}
+
+		for _, attack := range d.types {
+			e := d.Effectiveness(attack, s.Types)
+			switch {
+			case e == 0:
+				p.Immunities[attack]++
+			case e < 1:
+				p.Resistances[attack]++
+			case e > 1:
+				p.Weaknesses[attack]++
+			}
+		}
+	}
+
+	if len(unknown) != 0 {
+		return p, fmt.Errorf("unknown pokemons %+v in team: %+v", unknown, names)
+	}
+
+	return p, nil
+}
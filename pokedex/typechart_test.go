@@ -0,0 +1,52 @@
+package pokedex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEffectiveness(t *testing.T) {
+	tests := []struct {
+		gen    int
+		attack string
+		types  []string
+		want   float64
+	}{
+		{8, "Ice", []string{"Dragon", "Ground"}, 4},
+		{8, "Fire", []string{"Water", "Dragon"}, 0.25},
+		{8, "Ground", []string{"Electric", "Flying"}, 0},
+		{8, "Dark", []string{"Steel"}, 1},
+		{5, "Dark", []string{"Steel"}, 0.5},
+		{8, "Ghost", []string{"Psychic"}, 2},
+		{1, "Ghost", []string{"Psychic"}, 0},
+	}
+	for _, tt := range tests {
+		if got := ForGen(tt.gen).Effectiveness(tt.attack, tt.types); got != tt.want {
+			t.Errorf("gen %d %s on %q: got %v, want %v", tt.gen, tt.attack, tt.types, got, tt.want)
+		}
+	}
+}
+
+func TestProfile(t *testing.T) {
+	// Rotom-Wash is weak to Ground, Levitate is not taken into account
+	p, err := Load().Profile([]string{"Rotom-Wash", "Ferrothorn", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Profile{
+		Types:       map[string]int{"Electric": 1, "Water": 1, "Grass": 1, "Steel": 1},
+		Weaknesses:  map[string]int{"Grass": 1, "Ground": 1, "Fire": 1, "Fighting": 1},
+		Resistances: map[string]int{"Fire": 1, "Flying": 1, "Ice": 1, "Steel": 2, "Water": 2, "Dragon": 1, "Electric": 1, "Fairy": 1, "Grass": 1, "Normal": 1, "Psychic": 1, "Rock": 1},
+		Immunities:  map[string]int{"Poison": 1},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("got %+v, want %+v", *p, *want)
+	}
+
+	// The known pokemons are still counted
+	p, err = Load().Profile([]string{"Missingno", "Silvally-*"})
+	if err == nil || p.Types["Normal"] != 1 {
+		t.Errorf("got %+v, %v, want a Normal type and an error", *p, err)
+	}
+}
@@ -9,12 +9,11 @@ import (
 	"strings"
 )
 
-var ExpectedColumns int = 59
+// Columns of the teams csv output of ps-replay-parser, legacy format. These
+// are the same in all its versions, see csvLayouts for the others
 var PlayerIndex int = 0
 var TypeIndex int = 1
 var LeadIndex int = 2
-var PokemonsStart int = 4
-var PokemonsColumns int = 9
 
 type StatsFilter struct {
 	For     TeamFilter `json:"for"`
@@ -22,17 +21,31 @@ type StatsFilter struct {
 }
 
 type TeamFilter struct {
-	Player   []string   `json:"player"`   // Or between names
-	Pokemons [][]string `json:"pokemons"` // Or of ands
-	Type     []string   `json:"type"`     // Or between types
-	Lead     []string   `json:"lead"`
-	Dynamax  []string   `json:"dynamax"`
+	Player    []string   `json:"player"`   // Or between names
+	Pokemons  [][]string `json:"pokemons"` // Or of ands
+	Type      []string   `json:"type"`     // Or between types
+	Lead      []string   `json:"lead"`
+	Dynamax   []string   `json:"dynamax"`
+	Archetype []string   `json:"archetype"` // Or between archetypes (Rain, Sun+Electric, ...)
+	MinRating int        `json:"min_rating"`
+	MaxRating int        `json:"max_rating"`
+
+	// Minimum numbers of pokemons by type, jsonl only: {"Ground": 3} keeps
+	// the teams with three or more pokemons weak to Ground
+	Weaknesses  map[string]int `json:"weaknesses"`
+	Resistances map[string]int `json:"resistances"`
+	Immunities  map[string]int `json:"immunities"`
+
+	EndReason        []string `json:"end_reason"`         // Or between KO, forfeit, timer, tie and incomplete
+	ExcludeEndReason []string `json:"exclude_end_reason"` // Or between reasons
 }
 
 type Output struct {
-	Size    int  `json:"size"`
-	Lead    bool `json:"lead"`
-	Dynamax bool `json:"dynamax"`
+	Size    int    `json:"size"`
+	Lead    bool   `json:"lead"`
+	Dynamax bool   `json:"dynamax"`
+	Profile string `json:"profile"` // types, weaknesses, resistances or immunities
+	Unused  bool   `json:"unused"`
 }
 
 func main() {
@@ -67,14 +80,29 @@ func main() {
 		return
 	}
 
-	lines = filter.filterLines(lines)
+	teams := filter.filterTeams(ReadTeams(lines))
 
-	PrintComboUsage(output, lines)
+	PrintComboUsage(output, teams)
 }
 
-func PrintComboUsage(output Output, lines []string) {
+func PrintComboUsage(output Output, teams []*Team) {
 	if output.Lead {
-		getSpecific(lines, LeadIndex)
+		getSpecific(teams, func(t *Team) string { return t.Lead })
+		return
+	}
+
+	if output.Dynamax {
+		getSpecific(teams, func(t *Team) string { return t.DynamaxPokemon })
+		return
+	}
+
+	if output.Profile != "" {
+		getProfiles(teams, output.Profile)
+		return
+	}
+
+	if output.Unused {
+		getUnused(teams)
 		return
 	}
 
@@ -83,29 +111,22 @@ func PrintComboUsage(output Output, lines []string) {
 	scores := map[string]int{}
 	kills := map[string]int{}
 	deaths := map[string]int{}
-	for _, line := range lines {
-		mons := strings.Split(line, ";")
-		if len(mons) != ExpectedColumns {
-			continue
-		}
-
-		mons = mons[PokemonsStart:] // Cut player and lead and type
+	for _, team := range teams {
 		for _, combo := range combos {
 			comboKills := 0
 			comboDeaths := 0
 			keys := make([]string, output.Size)
 			i := 0
 			for j, index := range combo {
-				if mons[j*PokemonsColumns] == "" {
+				if j >= len(team.pokes) {
 					continue
 				}
 
 				if index == 1 {
-					keys[i] = mons[j*PokemonsColumns]
-					pokeKills, _ := strconv.Atoi(mons[j*PokemonsColumns+6]) // Beware index /!\
-					comboKills += pokeKills
-					pokeDeaths, _ := strconv.Atoi(mons[j*PokemonsColumns+7]) // Beware index /!\
-					comboDeaths += pokeDeaths
+					poke := team.pokes[j]
+					keys[i] = poke.Name
+					comboKills += poke.Kills
+					comboDeaths += poke.Deaths
 					i++
 				}
 			}
@@ -115,7 +136,7 @@ func PrintComboUsage(output Output, lines []string) {
 			cores[strings.Join(keys, ";")]++
 			kills[strings.Join(keys, ";")] += comboKills
 			deaths[strings.Join(keys, ";")] += comboDeaths
-			if mons[len(mons)-1] == "W" {
+			if team.Result == "W" {
 				scores[strings.Join(keys, ";")]++
 			}
 		}
@@ -141,42 +162,70 @@ func PrintComboUsage(output Output, lines []string) {
 	}
 }
 
-func (f StatsFilter) filterLines(lines []string) []string {
-	res := make([]string, len(lines))
-	j := 0
-	i := 0
-	for i < len(lines)-1 {
-		team1 := strings.Split(lines[i], ";")
-		team2 := strings.Split(lines[i+1], ";")
+// filterTeams keeps the teams matching For whose opponent matches Against,
+// the teams come by pair p1 then p2. A battle with an unreadable team is
+// skipped
+func (f StatsFilter) filterTeams(teams []*Team) []*Team {
+	var res []*Team
+	for i := 0; i < len(teams)-1; i += 2 {
+		team1, team2 := teams[i], teams[i+1]
+		if team1 == nil || team2 == nil {
+			continue
+		}
 		if f.For.matchTeam(team1) && f.Against.matchTeam(team2) {
-			res[j] = lines[i]
-			j++
+			res = append(res, team1)
 		}
 		if f.For.matchTeam(team2) && f.Against.matchTeam(team1) {
-			res[j] = lines[i+1]
-			j++
+			res = append(res, team2)
 		}
-
-		i += 2
 	}
 
-	return res[:j]
+	return res
 }
 
-func (f *TeamFilter) matchTeam(team []string) bool {
-	if len(f.Player) != 0 && !stringInSliceInsensitive(team[PlayerIndex], f.Player) {
+func (f *TeamFilter) matchTeam(team *Team) bool {
+	if len(f.Player) != 0 && !stringInSliceInsensitive(team.Player, f.Player) {
 		return false
 	}
 
-	if len(f.Lead) != 0 && !stringInSlice(team[LeadIndex], f.Lead) {
+	if len(f.Lead) != 0 && !stringInSlice(team.Lead, f.Lead) {
 		return false
 	}
 
-	if len(f.Type) != 0 && !stringInSlice(team[TypeIndex], f.Type) {
+	if len(f.Type) != 0 && !stringInSlice(team.Type, f.Type) {
 		return false
 	}
 
-	if len(f.Pokemons) != 0 && !f.pokemonsMatch(team[PokemonsStart:]) {
+	if len(f.Dynamax) != 0 && !stringInSlice(team.DynamaxPokemon, f.Dynamax) {
+		return false
+	}
+
+	if len(f.Archetype) != 0 && !stringInSlice(team.Archetype, f.Archetype) {
+		return false
+	}
+
+	if len(f.EndReason) != 0 && !stringInSlice(team.EndReason, f.EndReason) {
+		return false
+	}
+
+	if len(f.ExcludeEndReason) != 0 && stringInSlice(team.EndReason, f.ExcludeEndReason) {
+		return false
+	}
+
+	if f.MinRating != 0 || f.MaxRating != 0 {
+		rating := team.RatingBefore
+		if rating == 0 || rating < f.MinRating || f.MaxRating != 0 && rating > f.MaxRating {
+			return false
+		}
+	}
+
+	if !atLeast(team.Profile.counts("weaknesses"), f.Weaknesses) ||
+		!atLeast(team.Profile.counts("resistances"), f.Resistances) ||
+		!atLeast(team.Profile.counts("immunities"), f.Immunities) {
+		return false
+	}
+
+	if len(f.Pokemons) != 0 && !f.pokemonsMatch(team.names()) {
 		return false
 	}
 
@@ -193,19 +242,14 @@ func (f *TeamFilter) pokemonsMatch(team []string) bool {
 	return false
 }
 
-func getSpecific(lines []string, index int) {
+func getSpecific(teams []*Team, key func(*Team) string) {
 	cores := map[string]int{}
 	scores := map[string]int{}
 
-	for _, line := range lines {
-		mons := strings.Split(line, ";")
-		if len(mons) != ExpectedColumns {
-			continue
-		}
-
-		cores[mons[index]]++
-		if mons[len(mons)-1] == "W" {
-			scores[mons[index]]++
+	for _, team := range teams {
+		cores[key(team)]++
+		if team.Result == "W" {
+			scores[key(team)]++
 		}
 	}
 
@@ -217,6 +261,94 @@ func getSpecific(lines []string, index int) {
 	}
 }
 
+// getProfiles prints the usage and wins of the teams by number of pokemons of
+// each type in a part of their profile: type;count;usage;wins
+func getProfiles(teams []*Team, part string) {
+	cores := map[string]int{}
+	scores := map[string]int{}
+
+	for _, team := range teams {
+		for t, count := range team.Profile.counts(part) {
+			key := t + ";" + strconv.Itoa(count)
+			cores[key]++
+			if team.Result == "W" {
+				scores[key]++
+			}
+		}
+	}
+
+	for name, value := range cores {
+		fmt.Println(name + ";" + strconv.Itoa(value) + ";" + strconv.Itoa(scores[name]))
+	}
+}
+
+// getUnused prints how often each pokemon was brought but never switched in
+// and never used a move, with its turns on the field:
+// pokemon;teams;never_in;never_in_rate;never_moved;never_moved_rate;turns_active
+// The moves and turns are only known in the jsonl, the rates of never_moved
+// are on these teams only. When only some pokemons are brought (VGC) the ones
+// never switched in may have stayed home, they are not counted
+func getUnused(teams []*Team) {
+	brought := map[string]int{}
+	neverIn := map[string]int{}
+	withActivity := map[string]int{}
+	neverMoved := map[string]int{}
+	turns := map[string]int{}
+
+	for _, team := range teams {
+		partial := team.Brought != 0 && team.Brought < len(team.pokes)
+		for _, poke := range team.pokes {
+			if partial && poke.Entrances == 0 {
+				continue
+			}
+
+			brought[poke.Name]++
+			if poke.Entrances == 0 {
+				neverIn[poke.Name]++
+			}
+			if !team.activity {
+				continue
+			}
+
+			withActivity[poke.Name]++
+			turns[poke.Name] += poke.TurnsActive
+			if poke.MovesUsed == 0 {
+				neverMoved[poke.Name]++
+			}
+		}
+	}
+
+	for name, value := range brought {
+		fmt.Println(name + ";" +
+			strconv.Itoa(value) + ";" +
+			strconv.Itoa(neverIn[name]) + ";" +
+			rate(neverIn[name], value) + ";" +
+			strconv.Itoa(neverMoved[name]) + ";" +
+			rate(neverMoved[name], withActivity[name]) + ";" +
+			strconv.Itoa(turns[name]))
+	}
+}
+
+// rate returns n/total in percent, empty if total is 0
+func rate(n, total int) string {
+	if total == 0 {
+		return ""
+	}
+
+	return strconv.FormatFloat(100*float64(n)/float64(total), 'f', 1, 64)
+}
+
+// atLeast returns whether counts has at least the minimums of each key
+func atLeast(counts, mins map[string]int) bool {
+	for key, min := range mins {
+		if counts[key] < min {
+			return false
+		}
+	}
+
+	return true
+}
+
 func stringInSlice(p1 string, ps []string) bool {
 	for _, p2 := range ps {
 		if p1 == p2 {
@@ -331,21 +463,10 @@ func include(as, bs []string) bool {
 		return false
 	}
 
-	i := 0
-bloop:
-	for i < len(bs) {
-		j := 0
-		b := bs[i]
-		for j < len(as) {
-			a := as[j]
-			if a == b {
-				i++
-				continue bloop
-			}
-			j += 6
+	for _, b := range bs {
+		if !stringInSlice(b, as) {
+			return false
 		}
-
-		return false
 	}
 
 	return true
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Team is a team of the jsonl output of ps-replay-parser, only the fields
+// used here are read
+type Team struct {
+	Pokemons       map[string]*Pokemon `json:"pokemons"` // Key is Nickname
+	Lead           string              `json:"lead"`     // Nickname
+	Result         string              `json:"result"`
+	EndReason      string              `json:"end_reason"`
+	Player         string              `json:"player"`
+	Type           string              `json:"type"`
+	DynamaxPokemon string              `json:"dynamax_pokemon"` // Nickname
+	Archetype      string              `json:"archetype"`
+	RatingBefore   int                 `json:"rating_before"`
+	Brought        int                 `json:"brought"` // 0 in the csv
+	Profile        *Profile            `json:"profile"` // nil in the csv
+
+	pokes    []*Pokemon // Sorted by name, so the cores have a single order
+	activity bool       // Whether the turns active and moves used are known
+}
+
+// Profile is the type profile of a team, the counts are numbers of pokemons
+type Profile struct {
+	Types       map[string]int `json:"types"`
+	Weaknesses  map[string]int `json:"weaknesses"`
+	Resistances map[string]int `json:"resistances"`
+	Immunities  map[string]int `json:"immunities"`
+}
+
+// counts returns the counts of a part of the profile by its json name
+func (p *Profile) counts(part string) map[string]int {
+	if p == nil {
+		return nil
+	}
+
+	switch part {
+	case "types":
+		return p.Types
+	case "weaknesses":
+		return p.Weaknesses
+	case "resistances":
+		return p.Resistances
+	case "immunities":
+		return p.Immunities
+	default:
+		return nil
+	}
+}
+
+type Pokemon struct {
+	Name        string `json:"name"`
+	Kills       int    `json:"kills"`
+	Deaths      int    `json:"deaths"`
+	Entrances   int    `json:"entrances"`
+	TurnsActive int    `json:"turns_active"`
+	MovesUsed   int    `json:"moves_used"`
+}
+
+// ReadTeams reads the teams of a jsonl file, or of a legacy csv file of the
+// teams output. The lines which cannot be read are nil with a warning, so the
+// teams stay paired by battle
+func ReadTeams(lines []string) []*Team {
+	var teams []*Team
+	skipped := 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var team *Team
+		var err error
+		if strings.HasPrefix(line, "{") {
+			team, err = teamFromJSON(line)
+		} else {
+			team, err = teamFromCSV(line)
+		}
+		if err != nil {
+			skipped++
+		}
+
+		teams = append(teams, team)
+	}
+
+	if skipped != 0 {
+		counts := make([]int, 0, len(csvLayouts))
+		for count := range csvLayouts {
+			counts = append(counts, count)
+		}
+		sort.Ints(counts)
+		columns := make([]string, len(counts))
+		for i, count := range counts {
+			columns[i] = strconv.Itoa(count)
+		}
+		fmt.Fprintf(os.Stderr, "%d lines skipped, expected jsonl or a csv of %s columns\n", skipped,
+			strings.Join(columns, ", "))
+	}
+
+	return teams
+}
+
+func teamFromJSON(line string) (*Team, error) {
+	team := &Team{}
+	err := json.Unmarshal([]byte(line), team)
+	if err != nil {
+		return nil, err
+	}
+
+	team.activity = true
+	team.Lead = team.name(team.Lead)
+	team.DynamaxPokemon = team.name(team.DynamaxPokemon)
+	for _, poke := range team.Pokemons {
+		team.pokes = append(team.pokes, poke)
+	}
+	sort.Slice(team.pokes, func(i, j int) bool {
+		return team.pokes[i].Name < team.pokes[j].Name
+	})
+
+	return team, nil
+}
+
+// csvLayout is where the columns are in a version of the teams csv, -1 for
+// the columns it does not have. The result follows the pokemons
+type csvLayout struct {
+	archetype      int
+	rating         int // Rating before the battle
+	endReason      int
+	pokemonsStart  int
+	pokemonColumns int // name;item;move1..4;kills;deaths;switch-ins then the statuses
+}
+
+// csvLayouts are the versions of the teams csv by number of columns, so the
+// files written by older parsers can still be read. The layout is frozen at 75
+// columns, the newer fields are only in the jsonl
+var csvLayouts = map[int]csvLayout{
+	59: {-1, -1, -1, 4, 9}, // player;type;lead;battle_length;pokemons;result
+	60: {4, -1, -1, 5, 9},  // archetype
+	72: {4, -1, -1, 5, 11}, // statuses inflicted and received by each pokemon
+	74: {4, 5, -1, 7, 11},  // ratings before and after
+	75: {4, 5, 7, 8, 11},   // end reason
+}
+
+// teamFromCSV reads a line of the teams output, legacy format
+func teamFromCSV(line string) (*Team, error) {
+	cols := strings.Split(line, ";")
+	layout, ok := csvLayouts[len(cols)]
+	if !ok {
+		return nil, fmt.Errorf("%d columns", len(cols))
+	}
+	resultIndex := layout.pokemonsStart + 6*layout.pokemonColumns
+
+	team := &Team{
+		Player: cols[PlayerIndex],
+		Type:   cols[TypeIndex],
+		Lead:   cols[LeadIndex],
+		Result: cols[resultIndex],
+	}
+	if layout.archetype != -1 {
+		team.Archetype = cols[layout.archetype]
+	}
+	if layout.rating != -1 {
+		team.RatingBefore, _ = strconv.Atoi(cols[layout.rating])
+	}
+	if layout.endReason != -1 {
+		team.EndReason = cols[layout.endReason]
+	}
+
+	mons := cols[layout.pokemonsStart:resultIndex]
+	for i := 0; i+layout.pokemonColumns <= len(mons); i += layout.pokemonColumns {
+		if mons[i] == "" {
+			continue
+		}
+
+		kills, _ := strconv.Atoi(mons[i+6]) // Beware index /!\
+		deaths, _ := strconv.Atoi(mons[i+7])
+		entrances, _ := strconv.Atoi(mons[i+8])
+		poke := &Pokemon{
+			Name:      mons[i],
+			Kills:     kills,
+			Deaths:    deaths,
+			Entrances: entrances,
+		}
+		team.pokes = append(team.pokes, poke)
+	}
+
+	return team, nil
+}
+
+// name returns the name of the pokemon with this nickname if known
+func (t *Team) name(nick string) string {
+	if poke, ok := t.Pokemons[nick]; ok {
+		return poke.Name
+	}
+
+	return nick
+}
+
+func (t *Team) names() []string {
+	names := make([]string, len(t.pokes))
+	for i, poke := range t.pokes {
+		names[i] = poke.Name
+	}
+
+	return names
+}
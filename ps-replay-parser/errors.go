@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseError is an error on a replay. The replay is skipped unless the
+// reason is type, then its teams are kept with an Unknown type, or a warning
+// (identity, moveset, tier) the teams are kept despite
+type ParseError struct {
+	ReplayID string
+	Reason   string // read, fetch, parse, gen, type, identity, moveset or tier
+	Line     int    // 0 if the error is not on a line
+	Content  string // The offending line
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	if e.Line == 0 {
+		return e.ReplayID + ": " + e.Reason + ": " + e.Err.Error()
+	}
+
+	return fmt.Sprintf("%s: %s: line %d: %s: %q", e.ReplayID, e.Reason, e.Line, e.Err, e.Content)
+}
+
+// Cause returns the underlying error, see github.com/pkg/errors
+func (e *ParseError) Cause() error {
+	return e.Err
+}
+
+// toParseError returns the error as a ParseError on the replay
+func toParseError(err error, reason, replayID string) *ParseError {
+	perr, ok := err.(*ParseError)
+	if !ok {
+		perr = &ParseError{
+			Reason: reason,
+			Err:    err,
+		}
+	}
+
+	perr.ReplayID = replayID
+	return perr
+}
+
+// replayID returns the ID of the replay from its file or url:
+// gen8ou-1234567 for https://replay.pokemonshowdown.com/gen8ou-1234567 and
+// battle-gen8ou-1234567 for a battle-gen8ou-1234567.log.json server log
+func replayID(path string) string {
+	id := filepath.Base(path)
+	if strings.HasSuffix(id, ".log.json") {
+		return strings.TrimSuffix(id, ".log.json")
+	}
+	return strings.TrimSuffix(id, filepath.Ext(id))
+}
+
+// WriteErrorReport writes a line per error sorted by reason and replay:
+// reason;replay;line;error;content
+func WriteErrorReport(file string, errs []*ParseError) error {
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Reason != errs[j].Reason {
+			return errs[i].Reason < errs[j].Reason
+		}
+		return errs[i].ReplayID < errs[j].ReplayID
+	})
+
+	report := ""
+	for _, err := range errs {
+		report += err.Reason + ";" +
+			err.ReplayID + ";" +
+			strconv.Itoa(err.Line) + ";" +
+			strings.Replace(err.Err.Error(), "\n", " ", -1) + ";" +
+			err.Content + "\n"
+	}
+
+	return ioutil.WriteFile(file, []byte(report), 0644)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	log := readFixture(t, "gen8ou-1.log")
+	turn3 := strings.Index(log, "|turn|3\n")
+	line := strings.Count(log[:turn3], "\n") + 1
+
+	tests := []struct {
+		name, log, content string
+		line               int
+	}{
+		{"panic", log[:turn3] + "|-enditem|p1a\n" + log[turn3:], "|-enditem|p1a", line},
+		{"item", log[:turn3] + "|-heal|p1a|[from] item: Leftovers\n" + log[turn3:], "|-heal|p1a|[from] item: Leftovers", line},
+		{"no players", "|turn|1\n", "", 0},
+	}
+	for _, tt := range tests {
+		teams, err := ParsePokemonsFromHtml(tt.log)
+		if teams != nil {
+			t.Errorf("%s: got teams, want none", tt.name)
+		}
+
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%s: got %v, want a ParseError", tt.name, err)
+			continue
+		}
+		if perr.Reason != "parse" || perr.Line != tt.line || perr.Content != tt.content {
+			t.Errorf("%s: got %s line %d %q, want parse line %d %q", tt.name, perr.Reason, perr.Line, perr.Content,
+				tt.line, tt.content)
+		}
+	}
+}
+
+func TestParseErrorMessage(t *testing.T) {
+	err := toParseError(errors.New("no type found"), "type", replayID("https://replay.pokemonshowdown.com/gen8ou-1234567"))
+	if got := err.Error(); got != "gen8ou-1234567: type: no type found" {
+		t.Errorf("got %q", got)
+	}
+
+	err = toParseError(&ParseError{Reason: "parse", Line: 3, Content: "|-enditem|p1a", Err: errors.New("index out of range")},
+		"read", replayID("logs/gen8ou-1.log"))
+	if got := err.Error(); got != `gen8ou-1: parse: line 3: index out of range: "|-enditem|p1a"` {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
+)
+
+// replayJSON is a replay downloaded from https://replay.pokemonshowdown.com/<id>.json
+type replayJSON struct {
+	ID  string `json:"id"`
+	Log string `json:"log"`
+}
+
+// ExtractLog returns the battle log of a replay file, it can be a raw .log,
+// a replay page saved from the browser or a .json replay download
+func ExtractLog(content string) (string, error) {
+	trimmed := strings.TrimSpace(content)
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		return extractLogFromJSON(trimmed)
+	case strings.HasPrefix(trimmed, "<") || strings.Contains(content, "battle-log-data"):
+		return extractLogFromPage(content)
+	default:
+		return content, nil
+	}
+}
+
+func extractLogFromJSON(content string) (string, error) {
+	var replay replayJSON
+	err := json.Unmarshal([]byte(content), &replay)
+	if err != nil {
+		return "", errors.Wrap(err, "could not unmarshal replay json")
+	}
+
+	if replay.Log == "" {
+		return "", errors.New("no log in replay json")
+	}
+
+	return replay.Log, nil
+}
+
+// The log is in <script type="text/plain" class="battle-log-data">, older
+// pages have <script type="text/plain" class="log">
+func extractLogFromPage(content string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return "", errors.Wrap(err, "could not read replay page")
+	}
+
+	sel := doc.Find("script.battle-log-data")
+	if sel.Length() == 0 {
+		sel = doc.Find("script.log")
+	}
+	if sel.Length() == 0 {
+		return "", errors.New("no battle-log-data nor log in replay page")
+	}
+
+	// "</" is escaped in the page not to close the script
+	return strings.Replace(sel.First().Text(), `<\/`, "</", -1), nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExtractLog(t *testing.T) {
+	log := readFixture(t, "gen8ou-1.log")
+	b, err := json.Marshal(replayJSON{ID: "gen8ou-1", Log: log})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"raw":  log,
+		"json": string(b),
+		"page": `<!DOCTYPE html><html><body><div class="wrapper replay-wrapper">` +
+			`<script type="text/plain" class="battle-log-data">` + log + `</script></div></body></html>`,
+		"old page": `<html><body><script type="text/plain" class="log">` + log + `</script></body></html>`,
+	}
+	for name, content := range tests {
+		got, err := ExtractLog(content)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got != log {
+			t.Errorf("%s: got a log of %d bytes, want %d", name, len(got), len(log))
+		}
+	}
+}
+
+func TestExtractLogEscaped(t *testing.T) {
+	page := `<html><body><script type="text/plain" class="battle-log-data">|raw|<strong>1535<\/strong></script></body></html>`
+	got, err := ExtractLog(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "|raw|<strong>1535</strong>" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestExtractLogErrors(t *testing.T) {
+	tests := map[string]string{
+		"json without log": `{"id":"gen8ou-1"}`,
+		"invalid json":     `{"id":`,
+		"page without log": `<html><body><p>Could not connect</p></body></html>`,
+	}
+	for name, content := range tests {
+		if _, err := ExtractLog(content); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+}
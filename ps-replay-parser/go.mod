@@ -4,5 +4,8 @@ go 1.13
 
 require (
 	github.com/PuerkitoBio/goquery v1.5.0
+	github.com/nailec/ps-usage-stats/pokedex v0.0.0
 	github.com/pkg/errors v0.8.1
 )
+
+replace github.com/nailec/ps-usage-stats/pokedex => ../pokedex
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Hazard is an entry hazard set by a team on its opponent's side
+type Hazard struct {
+	Name        string `json:"name"`
+	Layers      int    `json:"layers"`
+	Setter      string `json:"setter"` // Name of the pokemon who set the first layer, "" if unknown
+	TurnSet     int    `json:"turn_set"`
+	TurnRemoved int    `json:"turn_removed"` // 0 if still up at the end of the battle
+	RemovedBy   string `json:"removed_by"`   // Name of the pokemon, can be on any side
+	RemovalMove string `json:"removal_move"` // Defog, Rapid Spin, Court Change, ...
+	Damage      int    `json:"damage"`       // HP percentage dealt to the opponent's pokemons
+}
+
+var hazardNames = []string{"Stealth Rock", "Spikes", "Toxic Spikes", "Sticky Web"}
+
+// TurnsUp returns for how many turns the hazard stayed on the field
+func (h *Hazard) TurnsUp(battleLength int) int {
+	if h.TurnRemoved == 0 {
+		return battleLength - h.TurnSet
+	}
+
+	return h.TurnRemoved - h.TurnSet
+}
+
+// activeHazard returns the hazard of this name set by the team that is still up
+func activeHazard(team *Team, name string) *Hazard {
+	for i := len(team.Hazards) - 1; i >= 0; i-- {
+		if team.Hazards[i].Name == name && team.Hazards[i].TurnRemoved == 0 {
+			return team.Hazards[i]
+		}
+	}
+
+	return nil
+}
+
+// setHazard adds a hazard (or a layer) set by the pokemon of the team, its
+// nickname is "" when an ability set it
+func setHazard(team *Team, pokeNick, name string, turn int) {
+	setter := pokeNick
+	if poke, ok := team.Pokemons[pokeNick]; ok {
+		poke.HazardsSet++
+		setter = poke.Name
+	}
+
+	if h := activeHazard(team, name); h != nil {
+		h.Layers++
+		return
+	}
+
+	team.Hazards = append(team.Hazards, &Hazard{
+		Name:    name,
+		Layers:  1,
+		Setter:  setter,
+		TurnSet: turn,
+	})
+}
+
+// removeHazard ends a hazard set by the team, remover is from the team `by`
+func removeHazard(team, by *Team, name, removerNick, move string, turn int) *Hazard {
+	h := activeHazard(team, name)
+	if h == nil {
+		return nil
+	}
+
+	h.TurnRemoved = turn
+	h.RemovedBy = removerNick
+	h.RemovalMove = move
+	if poke, ok := by.Pokemons[removerNick]; ok {
+		poke.HazardsRemoved++
+		h.RemovedBy = poke.Name
+	}
+
+	return h
+}
+
+// courtChange swaps the hazards of both sides, they count as removed by the
+// user and set again by it on the other side
+func courtChange(teams map[string]*Team, pID, pokeNick string, turn int) {
+	setter := pokeNick
+	if poke, ok := teams[pID].Pokemons[pokeNick]; ok {
+		setter = poke.Name
+	}
+
+	swapped := map[string][]*Hazard{}
+	for _, side := range []string{"p1", "p2"} {
+		for _, h := range teams[side].Hazards {
+			if h.TurnRemoved != 0 {
+				continue
+			}
+			removeHazard(teams[side], teams[pID], h.Name, pokeNick, "Court Change", turn)
+			swapped[getOpp(side)] = append(swapped[getOpp(side)], h)
+		}
+	}
+
+	for side, hazards := range swapped {
+		for _, h := range hazards {
+			teams[side].Hazards = append(teams[side].Hazards, &Hazard{
+				Name:    h.Name,
+				Layers:  h.Layers,
+				Setter:  setter,
+				TurnSet: turn,
+			})
+		}
+	}
+}
+
+func isHazard(name string) bool {
+	return stringInSlice(name, hazardNames)
+}
+
+func stringInSlice(s string, ss []string) bool {
+	for _, s2 := range ss {
+		if s == s2 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// |-sidestart|p1: Alice|move: Stealth Rock
+// returns side and condition
+func getSideStartInfo(line string) (string, string) {
+	expected := regexp.MustCompile(`\|-sidestart\|(p(1|2)): [^\|]*\|(move: )?([^\|]*)`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", ""
+	}
+	return res[1], res[4]
+}
+
+// |-sideend|p1: Alice|Stealth Rock|[from] move: Defog|[of] p2a: Corviknight
+// returns side, condition, removal move, remover's player and nickname
+func getSideEndInfo(line string) (string, string, string, string, string) {
+	expected := regexp.MustCompile(`\|-sideend\|(p(1|2)): [^\|]*\|(move: )?([^\|]*)(\|\[from\] (move: )?([^\|]*))?(\|\[of\] (p(1|2))[a-d]: ([^\|]*))?`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", "", "", "", ""
+	}
+	return res[1], res[4], res[7], res[9], res[11]
+}
+
+// |-activate|p1a: Cinderace|move: Court Change
+// returns player and nickname
+func getCourtChangeInfo(line string) (string, string) {
+	expected := regexp.MustCompile(`\|-activate\|(p(1|2))[a-d]: ([^\|]*)\|move: Court Change`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", ""
+	}
+	return res[1], res[3]
+}
+
+// |-damage|p1a: Ferrothorn|91/100 brn|[from] Stealth Rock
+// returns player, nickname, hp and source
+func getHPChange(line string) (string, string, int, string) {
+	expected := regexp.MustCompile(`\|-(damage|heal)\|(p(1|2))[a-d]: ([^\|]*)\|([^\|]*)(\|\[from\] ([^\|]*))?`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", "", 0, ""
+	}
+	return res[2], res[4], parseHP(res[5]), res[7]
+}
+
+// |switch|p1a: Bird|Pelipper, F|100/100
+func getSwitchHP(line string) int {
+	split := strings.Split(line, "|")
+	if len(split) < 5 {
+		return 100
+	}
+
+	return parseHP(split[4])
+}
+
+// parseHP returns the percentage of hp left from a status like "91/100 brn"
+func parseHP(status string) int {
+	status = strings.Split(status, " ")[0]
+	split := strings.Split(status, "/")
+	hp, err := strconv.Atoi(split[0])
+	if err != nil {
+		return 0
+	}
+
+	if len(split) < 2 {
+		return hp
+	}
+
+	max, err := strconv.Atoi(split[1])
+	if err != nil || max == 0 {
+		return hp
+	}
+
+	return hp * 100 / max
+}
@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+func TestHazards(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	// p2's Stealth Rock on Alice's side, then Alice's on Bob's side
+	want := map[string][]Hazard{
+		"p1": {
+			{Name: "Stealth Rock", Layers: 1, Setter: "Swampert", TurnSet: 2, TurnRemoved: 6,
+				RemovedBy: "Corviknight", RemovalMove: "Defog", Damage: 12},
+			{Name: "Spikes", Layers: 2, Setter: "Ferrothorn", TurnSet: 4, TurnRemoved: 6,
+				RemovedBy: "Corviknight", RemovalMove: "Defog"},
+		},
+		"p2": {
+			{Name: "Stealth Rock", Layers: 1, Setter: "Landorus-Therian", TurnSet: 1, TurnRemoved: 6,
+				RemovedBy: "Corviknight", RemovalMove: "Defog", Damage: 3},
+		},
+	}
+	for pID, hazards := range want {
+		got := teams[pID].Hazards
+		if len(got) != len(hazards) {
+			t.Errorf("%s: got %d hazards, want %d", pID, len(got), len(hazards))
+			continue
+		}
+		for i, h := range hazards {
+			if *got[i] != h {
+				t.Errorf("%s hazard %d: got %+v, want %+v", pID, i, *got[i], h)
+			}
+		}
+	}
+
+	ferro := teams["p1"].Pokemons["Ferrothorn"]
+	if ferro.HazardsSet != 2 || ferro.HazardDamage != 3 {
+		t.Errorf("Ferrothorn: got %d set and %d damage, want 2 and 3", ferro.HazardsSet, ferro.HazardDamage)
+	}
+	corv := teams["p2"].Pokemons["Corviknight"]
+	if corv.HazardsRemoved != 3 || corv.HazardDamage != 12 {
+		t.Errorf("Corviknight: got %d removed and %d damage, want 3 and 12", corv.HazardsRemoved, corv.HazardDamage)
+	}
+}
+
+func TestHazardTurnsUp(t *testing.T) {
+	h := &Hazard{TurnSet: 2}
+	if got := h.TurnsUp(9); got != 7 {
+		t.Errorf("still up: got %d, want 7", got)
+	}
+
+	h.TurnRemoved = 6
+	if got := h.TurnsUp(9); got != 4 {
+		t.Errorf("removed: got %d, want 4", got)
+	}
+}
+
+func TestParseHP(t *testing.T) {
+	tests := map[string]int{
+		"100/100":    100,
+		"91/100 brn": 91,
+		"0 fnt":      0,
+		"150/300":    50,
+	}
+	for status, want := range tests {
+		if got := parseHP(status); got != want {
+			t.Errorf("parseHP(%q): got %d, want %d", status, got, want)
+		}
+	}
+}
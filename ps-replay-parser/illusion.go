@@ -0,0 +1,134 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// disguise is what a pokemon did since its switch, moved to Zoroark if it
+// was an Illusion
+type disguise struct {
+	nick      string
+	moves     []string // Moves added to the pokemon's moveset since its switch
+	kills     int
+	movesUsed int
+	turns     int  // Turns started on the field
+	lead      bool // Sent before the first turn
+}
+
+// disguiseOf returns the disguise of the active pokemon of the side with this
+// nickname, nil if it is not active
+func disguiseOf(disguises map[string]*disguise, pID, nick string) *disguise {
+	for pos, d := range disguises {
+		if strings.HasPrefix(pos, pID) && d.nick == nick {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// isTransformed returns whether the active pokemon of the side with this
+// nickname transformed
+func isTransformed(transformed map[string]string, pID, nick string) bool {
+	for pos, n := range transformed {
+		if strings.HasPrefix(pos, pID) && n == nick {
+			return true
+		}
+	}
+
+	return false
+}
+
+// breakIllusion moves what the disguise did to the pokemon who was behind it
+func breakIllusion(team *Team, d *disguise, nick string) {
+	fake, ok := team.Pokemons[d.nick]
+	real := team.Pokemons[nick]
+	if !ok || real == nil || d.nick == nick {
+		return
+	}
+
+	for _, move := range d.moves {
+		removeMove(fake.Moves, move)
+		addMove(real.Moves, move)
+	}
+
+	fake.Kills -= d.kills
+	real.Kills += d.kills
+	fake.Entrances--
+	real.Entrances++
+	fake.MovesUsed -= d.movesUsed
+	real.MovesUsed += d.movesUsed
+	fake.TurnsActive -= d.turns
+	real.TurnsActive += d.turns
+	real.hp = fake.hp
+
+	// The disguise only took a lead's place if it was sent before the first turn
+	if d.lead {
+		if team.Lead == d.nick {
+			team.Lead = nick
+		}
+		for i := len(team.Leads) - 1; i >= 0; i-- {
+			if team.Leads[i] == fake.Name {
+				team.Leads[i] = real.Name
+				break
+			}
+		}
+	}
+	if len(team.Switches) != 0 {
+		last := team.Switches[len(team.Switches)-1]
+		if last.In == fake.Name {
+			last.In = real.Name
+		}
+	}
+}
+
+// removeMove removes the move and keeps the known moves first
+func removeMove(a []string, s string) {
+	for i, v := range a {
+		if v != s {
+			continue
+		}
+
+		copy(a[i:], a[i+1:])
+		a[len(a)-1] = ""
+		return
+	}
+}
+
+// |replace|p1a: Zoroark|Zoroark, L84, M
+// returns player, nickname and name
+func getReplaceInfo(line string) (string, string, string) {
+	expected := regexp.MustCompile(`\|replace\|(p(1|2))[a-d]: ([^\|]*)\|([^,|]*)`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", "", ""
+	}
+	return res[1], res[3], res[4]
+}
+
+// |-transform|p1a: Ditto|p2a: Landorus|[from] ability: Imposter
+// returns player and nickname of the transformed pokemon
+func getTransformInfo(line string) (string, string) {
+	expected := regexp.MustCompile(`\|-transform\|(p(1|2))[a-d]: ([^\|]*)\|`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", ""
+	}
+	return res[1], res[3]
+}
+
+// |-start|p1a: Mr. Mime|Mimic|Thunderbolt
+// |-activate|p1a: Smeargle|move: Sketch|Spore
+// returns player, nickname and the move copied, it is not part of the set
+func getCopiedMoveInfo(line string) (string, string, string) {
+	expected := regexp.MustCompile(`\|-(start|activate)\|(p(1|2))[a-d]: ([^\|]*)\|(move: )?(Mimic|Sketch)\|([^\|]*)`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", "", ""
+	}
+	return res[2], res[4], res[7]
+}
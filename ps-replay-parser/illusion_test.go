@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIllusion(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-2.log")
+	p1 := teams["p1"]
+
+	// Zoroark led as Swampert
+	zoroark, swampert := p1.Pokemons["Zoroark"], p1.Pokemons["Swampert"]
+	if zoroark.Moves[0] != "Knock Off" || zoroark.Entrances != 1 || swampert.Moves[0] != "" || swampert.Entrances != 0 {
+		t.Errorf("got Zoroark %q %d, Swampert %q %d", zoroark.Moves, zoroark.Entrances, swampert.Moves, swampert.Entrances)
+	}
+	if p1.Lead != "Zoroark" || !reflect.DeepEqual(p1.Leads, []string{"Zoroark"}) || !reflect.DeepEqual(teams["p2"].OppLeads, []string{"Zoroark"}) {
+		t.Errorf("got lead %s, leads %q, opponent's leads %q", p1.Lead, p1.Leads, teams["p2"].OppLeads)
+	}
+	if zoroark.TurnsActive != 2 || zoroark.MovesUsed != 1 || swampert.TurnsActive != 0 || swampert.MovesUsed != 0 {
+		t.Errorf("got Zoroark %d turns %d moves, Swampert %d turns %d moves", zoroark.TurnsActive, zoroark.MovesUsed, swampert.TurnsActive, swampert.MovesUsed)
+	}
+	if out := p1.Switches[0].Out; out != "Zoroark" {
+		t.Errorf("got switch out of %s, want Zoroark", out)
+	}
+
+	// The moves of the transformed Ditto and the copied ones are not part of the sets
+	if ditto := p1.Pokemons["Ditto"]; ditto.Moves[0] != "" || ditto.Ability != "Imposter" {
+		t.Errorf("got Ditto %q %s", ditto.Moves, ditto.Ability)
+	}
+	want := []string{"Mimic", "Psychic", "", ""}
+	if got := p1.Pokemons["Mr. Mime"].Moves; !reflect.DeepEqual(got, want) {
+		t.Errorf("got Mr. Mime %q, want %q", got, want)
+	}
+
+	// The temporary formes keep the name of the team
+	if name := teams["p2"].Pokemons["Aegislash"].Name; name != "Aegislash" {
+		t.Errorf("got %s, want Aegislash", name)
+	}
+}
+
+func TestIllusionAfterLead(t *testing.T) {
+	log := `|player|p1|Alice|1|
+|player|p2|Bob|2|
+|poke|p1|Zoroark, M|
+|poke|p1|Swampert, M|
+|poke|p1|Mr. Mime, M|
+|poke|p2|Landorus-Therian, M|
+|teampreview
+|start
+|switch|p1a: Swampert|Swampert, M|100/100
+|switch|p2a: Landorus|Landorus-Therian, M|100/100
+|turn|1
+|switch|p1a: Mr. Mime|Mr. Mime, M|100/100
+|move|p2a: Landorus|Earthquake|p1a: Mr. Mime
+|replace|p1a: Zoroark|Zoroark, M
+|-end|p1a: Zoroark|Illusion
+|turn|2
+|win|Alice
+`
+	p1 := parseLog(t, log)["p1"]
+
+	if p1.Lead != "Swampert" || p1.Leads[0] != "Swampert" {
+		t.Errorf("got lead %s, leads %q, want Swampert", p1.Lead, p1.Leads)
+	}
+	if in := p1.Switches[0].In; in != "Zoroark" {
+		t.Errorf("got switch in of %s, want Zoroark", in)
+	}
+	if mime := p1.Pokemons["Mr. Mime"]; mime.Entrances != 0 {
+		t.Errorf("got %d Mr. Mime entrances, want 0", mime.Entrances)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// |teampreview|4
+// returns how many pokemons are brought, 0 if not specified
+func getTeamPreviewInfo(line string) int {
+	expected := regexp.MustCompile(`\|teampreview\|(\d+)`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(res[1])
+	return n
+}
+
+// GetLeadMatchups returns, keyed by leads and opposing leads, how many times
+// they met and how many times the team with the leads won
+func GetLeadMatchups(teams []*Team) (map[string]int, map[string]int) {
+	matchups := map[string]int{}
+	wins := map[string]int{}
+	for _, team := range teams {
+		if team == nil || len(team.Leads) == 0 {
+			continue
+		}
+
+		key := strings.Join(team.Leads, "+") + "\t" + strings.Join(team.OppLeads, "+")
+		matchups[key]++
+		if team.Result == "W" {
+			wins[key]++
+		}
+	}
+
+	return matchups, wins
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPreviewAndLeads(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	preview := []string{"Pelipper", "Swampert", "Ferrothorn", "Toxapex", "Zapdos", "Barraskewda"}
+	if !reflect.DeepEqual(teams["p1"].Preview, preview) {
+		t.Errorf("preview: got %q, want %q", teams["p1"].Preview, preview)
+	}
+	if teams["p1"].Brought != 6 {
+		t.Errorf("brought: got %d, want 6", teams["p1"].Brought)
+	}
+	if !reflect.DeepEqual(teams["p1"].Leads, []string{"Pelipper"}) ||
+		!reflect.DeepEqual(teams["p1"].OppLeads, []string{"Landorus-Therian"}) {
+		t.Errorf("leads: got %q against %q", teams["p1"].Leads, teams["p1"].OppLeads)
+	}
+}
+
+func TestGetLeadMatchups(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	matchups, wins := GetLeadMatchups([]*Team{teams["p1"], teams["p2"], nil})
+	if matchups["Pelipper\tLandorus-Therian"] != 1 || wins["Pelipper\tLandorus-Therian"] != 1 {
+		t.Errorf("Pelipper: got %d matchups and %d wins, want 1 and 1",
+			matchups["Pelipper\tLandorus-Therian"], wins["Pelipper\tLandorus-Therian"])
+	}
+	if matchups["Landorus-Therian\tPelipper"] != 1 || wins["Landorus-Therian\tPelipper"] != 0 {
+		t.Errorf("Landorus-Therian: got %d matchups and %d wins, want 1 and 0",
+			matchups["Landorus-Therian\tPelipper"], wins["Landorus-Therian\tPelipper"])
+	}
+}
+
+func TestGetTeamPreviewInfo(t *testing.T) {
+	if got := getTeamPreviewInfo("|teampreview|4"); got != 4 {
+		t.Errorf("got %d, want 4", got)
+	}
+	if got := getTeamPreviewInfo("|teampreview"); got != 0 {
+		t.Errorf("got %d, want 0", got)
+	}
+}
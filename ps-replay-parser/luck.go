@@ -0,0 +1,238 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Luck counts the random events of the battle for and against the team. The
+// secondary effects are the statuses and confusions inflicted by a move which
+// damaged the target and the flinches, so Nuzzle counts as one
+type Luck struct {
+	Crits            int `json:"crits"` // Landed by the team
+	CritsTaken       int `json:"crits_taken"`
+	Misses           int `json:"misses"` // Moves of the team which missed
+	OppMisses        int `json:"opp_misses"`
+	Secondaries      int `json:"secondaries"` // Procs of the team's moves
+	SecondariesTaken int `json:"secondaries_taken"`
+	FullParas        int `json:"full_paras"` // Turns the team's pokemons were fully paralyzed
+	OppFullParas     int `json:"opp_full_paras"`
+	SleepTurns       int `json:"sleep_turns"` // Turns the team's pokemons could not move
+	FreezeTurns      int `json:"freeze_turns"`
+	HaxKOs           int `json:"hax_kos"` // KOs by the team of a pokemon it crit or hit with a secondary effect this turn
+	HaxKOsTaken      int `json:"hax_kos_taken"`
+}
+
+// Score returns the hax the team got minus the hax it suffered, the sleep and
+// freeze turns are left out
+func (l *Luck) Score() int {
+	return l.Crits - l.CritsTaken +
+		l.OppMisses - l.Misses +
+		l.Secondaries - l.SecondariesTaken +
+		l.OppFullParas - l.FullParas
+}
+
+// add adds the counts of o
+func (l *Luck) add(o *Luck) {
+	l.Crits += o.Crits
+	l.CritsTaken += o.CritsTaken
+	l.Misses += o.Misses
+	l.OppMisses += o.OppMisses
+	l.Secondaries += o.Secondaries
+	l.SecondariesTaken += o.SecondariesTaken
+	l.FullParas += o.FullParas
+	l.OppFullParas += o.OppFullParas
+	l.SleepTurns += o.SleepTurns
+	l.FreezeTurns += o.FreezeTurns
+	l.HaxKOs += o.HaxKOs
+	l.HaxKOsTaken += o.HaxKOsTaken
+}
+
+// luckTracker reads the random events of a battle line by line, apart from
+// the other branches of the parsing
+type luckTracker struct {
+	luck    map[string]*Luck // By player ID
+	turn    int
+	moverID string          // Player of the last move
+	damaged map[string]bool // Pokemons damaged by the last move, "p2: Heatran"
+	haxed   map[string]bool // Pokemons crit or hit by a secondary effect this turn
+}
+
+func newLuckTracker() *luckTracker {
+	return &luckTracker{
+		luck:    map[string]*Luck{"p1": {}, "p2": {}},
+		damaged: map[string]bool{},
+		haxed:   map[string]bool{},
+	}
+}
+
+func (t *luckTracker) read(line string, turn int) {
+	if turn != t.turn {
+		t.turn = turn
+		t.haxed = map[string]bool{}
+	}
+
+	switch {
+	case strings.HasPrefix(line, "|move|"):
+		pID, _, _ := getMoveInfo(line)
+		t.moverID = pID
+		t.damaged = map[string]bool{}
+
+	case strings.HasPrefix(line, "|-damage|"):
+		pID, pokeNick, _, from := getHPChange(line)
+		if pID != "" && from == "" && t.moverID == getOpp(pID) {
+			t.damaged[pID+": "+pokeNick] = true
+		}
+
+	case strings.HasPrefix(line, "|-crit|"):
+		pID, pokeNick := getCritInfo(line)
+		if pID == "" {
+			return
+		}
+		opp := getOpp(pID)
+		t.luck[opp].Crits++
+		t.luck[pID].CritsTaken++
+		t.haxed[pID+": "+pokeNick] = true
+
+	case strings.HasPrefix(line, "|-miss|"):
+		pID := getMissInfo(line)
+		if pID == "" {
+			return
+		}
+		opp := getOpp(pID)
+		t.luck[pID].Misses++
+		t.luck[opp].OppMisses++
+
+	case strings.HasPrefix(line, "|cant|"):
+		pID, pokeNick, reason := getCantReason(line)
+		if pID == "" {
+			return
+		}
+		opp := getOpp(pID)
+		switch reason {
+		case "par":
+			t.luck[pID].FullParas++
+			t.luck[opp].OppFullParas++
+		case "slp":
+			t.luck[pID].SleepTurns++
+		case "frz":
+			t.luck[pID].FreezeTurns++
+		case "flinch":
+			t.luck[opp].Secondaries++
+			t.luck[pID].SecondariesTaken++
+			t.haxed[pID+": "+pokeNick] = true
+		}
+
+	case strings.HasPrefix(line, "|-status|"), strings.HasPrefix(line, "|-start|"):
+		pID, pokeNick, ok := getSecondaryInfo(line)
+		if !ok || !t.damaged[pID+": "+pokeNick] {
+			return
+		}
+		opp := getOpp(pID)
+		t.luck[opp].Secondaries++
+		t.luck[pID].SecondariesTaken++
+		t.haxed[pID+": "+pokeNick] = true
+
+	case strings.HasPrefix(line, "|faint|"):
+		pID, pokeNick := getFaintInfo(line)
+		opp := getOpp(pID)
+		if t.haxed[pID+": "+pokeNick] {
+			t.luck[opp].HaxKOs++
+			t.luck[pID].HaxKOsTaken++
+		}
+	}
+}
+
+// |-crit|p2a: Heatran
+// returns player and nickname of the pokemon hit
+func getCritInfo(line string) (string, string) {
+	expected := regexp.MustCompile(`^\|-crit\|(p(1|2))[a-d]: ([^\|]*)`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", ""
+	}
+	return res[1], res[3]
+}
+
+// |-miss|p1a: Swampert|p2a: Heatran
+// returns the player of the pokemon who missed
+func getMissInfo(line string) string {
+	expected := regexp.MustCompile(`^\|-miss\|(p(1|2))[a-d]: `)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return ""
+	}
+	return res[1]
+}
+
+// |cant|p1a: Zapdos|par
+// returns player, nickname and the reason
+func getCantReason(line string) (string, string, string) {
+	expected := regexp.MustCompile(`^\|cant\|(p(1|2))[a-d]: ([^\|]*)\|([^\|]*)`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", "", ""
+	}
+	return res[1], res[3], res[4]
+}
+
+// |-status|p2a: Heatran|par
+// |-start|p2a: Heatran|confusion
+// returns player and nickname of a status or confusion without a [from], ok
+// is false for the other lines
+func getSecondaryInfo(line string) (string, string, bool) {
+	expected := regexp.MustCompile(`^\|(-status\|(p(1|2))[a-d]: ([^\|]*)\|(brn|par|psn|tox|slp|frz)|-start\|(p(1|2))[a-d]: ([^\|]*)\|confusion)$`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", "", false
+	}
+	if res[2] != "" {
+		return res[2], res[4], true
+	}
+	return res[6], res[8], true
+}
+
+// GetLuckReport returns the luck of each player summed over the teams, with
+// the number of battles and wins
+func GetLuckReport(teams []*Team) (map[string]*Luck, map[string]int, map[string]int) {
+	luck := map[string]*Luck{}
+	battles := map[string]int{}
+	wins := map[string]int{}
+	for _, team := range teams {
+		if team == nil || team.Luck == nil {
+			continue
+		}
+
+		if _, ok := luck[team.Player]; !ok {
+			luck[team.Player] = &Luck{}
+		}
+		luck[team.Player].add(team.Luck)
+		battles[team.Player]++
+		if team.Result == "W" {
+			wins[team.Player]++
+		}
+	}
+
+	return luck, battles, wins
+}
+
+// formatLuck returns the counts separated by sep, in the order of the struct
+// then the score
+func formatLuck(l *Luck, sep string) string {
+	counts := []int{
+		l.Crits, l.CritsTaken, l.Misses, l.OppMisses,
+		l.Secondaries, l.SecondariesTaken, l.FullParas, l.OppFullParas,
+		l.SleepTurns, l.FreezeTurns, l.HaxKOs, l.HaxKOsTaken, l.Score(),
+	}
+
+	res := make([]string, len(counts))
+	for i, c := range counts {
+		res[i] = strconv.Itoa(c)
+	}
+	return strings.Join(res, sep)
+}
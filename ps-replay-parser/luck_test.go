@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLuck(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	// Ferrothorn missed, Corviknight crit Zapdos for the KO, Barraskewda
+	// flinched Corviknight then KOed it. Will-O-Wisp is not a secondary effect
+	want := map[string]*Luck{
+		"p1": {CritsTaken: 1, Misses: 1, Secondaries: 1, HaxKOs: 1, HaxKOsTaken: 1},
+		"p2": {Crits: 1, OppMisses: 1, SecondariesTaken: 1, HaxKOs: 1, HaxKOsTaken: 1},
+	}
+	for id, l := range want {
+		if got := teams[id].Luck; !reflect.DeepEqual(got, l) {
+			t.Errorf("%s: got %+v, want %+v", id, *got, *l)
+		}
+	}
+	if score := teams["p1"].Luck.Score(); score != -1 {
+		t.Errorf("got a score of %d, want -1", score)
+	}
+}
+
+func TestHaxKOTarget(t *testing.T) {
+	log := `|player|p1|Alice|1|
+|player|p2|Bob|2|
+|gametype|doubles
+|start
+|switch|p1a: Zapdos|Zapdos|100/100
+|switch|p1b: Ferrothorn|Ferrothorn, M|100/100
+|switch|p2a: Corviknight|Corviknight, M|100/100
+|switch|p2b: Heatran|Heatran, M|100/100
+|turn|1
+|move|p2a: Corviknight|Brave Bird|p1a: Zapdos
+|-crit|p1a: Zapdos
+|-damage|p1a: Zapdos|40/100
+|move|p2b: Heatran|Lava Plume|p1b: Ferrothorn
+|-damage|p1b: Ferrothorn|0 fnt
+|faint|p1b: Ferrothorn
+|move|p2b: Heatran|Lava Plume|p1a: Zapdos
+|-damage|p1a: Zapdos|10/100
+|-status|p1a: Zapdos|brn
+|turn|2
+|cant|p1a: Zapdos|par
+|move|p2b: Heatran|Lava Plume|p1a: Zapdos
+|-damage|p1a: Zapdos|0 fnt
+|faint|p1a: Zapdos
+|win|Bob
+`
+	teams := parseLog(t, log)
+
+	// The crit on Zapdos does not make the KO of Ferrothorn a hax KO, the
+	// burn of the previous turn does not count for the KO of Zapdos
+	want := &Luck{Crits: 1, Secondaries: 1, OppFullParas: 1}
+	if got := teams["p2"].Luck; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", *got, *want)
+	}
+}
+
+func TestGetLuckReport(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+	list := []*Team{teams["p1"], teams["p2"], teams["p1"], nil}
+
+	luck, battles, wins := GetLuckReport(list)
+	if battles["Alice"] != 2 || wins["Alice"] != 2 || battles["Bob"] != 1 || wins["Bob"] != 0 {
+		t.Errorf("got battles %v, wins %v", battles, wins)
+	}
+	if l := luck["Alice"]; l.Misses != 2 || l.HaxKOs != 2 {
+		t.Errorf("got %+v", *l)
+	}
+	// The counts are added to a new Luck
+	if teams["p1"].Luck.Misses != 1 {
+		t.Errorf("got %d misses on the team, want 1", teams["p1"].Luck.Misses)
+	}
+}
+
+func TestFormatLuck(t *testing.T) {
+	l := &Luck{Crits: 2, CritsTaken: 1, OppMisses: 1, SleepTurns: 3}
+	if got := formatLuck(l, ";"); got != "2;1;0;1;0;0;0;0;3;0;0;0;2" {
+		t.Errorf("got %q", got)
+	}
+}
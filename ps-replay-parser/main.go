@@ -1,23 +1,36 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
 )
 
+const pokemonColumns = 11 // Columns of a pokemon in the teams output
+const errorReportFile = "errors.csv"
+const pasteDir = "pastes"
+
 func main() {
 	args := os.Args
-	if len(args) != 4 {
-		fmt.Println("go run main.go filename format stats/teams")
+	if len(args) != 4 && len(args) != 5 {
+		fmt.Println("go run main.go filename format stats/teams [workers]")
 		return
 	}
 
 	format := args[2]
+	workers := runtime.NumCPU()
+	if len(args) == 5 {
+		var err error
+		workers, err = strconv.Atoi(args[4])
+		if err != nil {
+			fmt.Println("cannot parse workers: ", err)
+			return
+		}
+	}
 
 	fileInfo, err := os.Stat(args[1])
 	if err != nil {
@@ -29,16 +42,11 @@ func main() {
 
 	var paths []string
 	if isLogs {
-		files, err := ioutil.ReadDir(args[1])
+		paths, err = GetPathsFromDir(args[1], format)
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
-
-		paths = make([]string, len(files))
-		for i, file := range files {
-			paths[i] = filepath.Join(args[1], file.Name())
-		}
 	} else {
 		paths, err = GetURLsFromFile(args[1], format)
 		if err != nil {
@@ -47,30 +55,112 @@ func main() {
 		}
 	}
 
-	switch args[3] {
-	case "stats":
-		res, err := GetStats(paths, isLogs)
-		if err != nil {
-			fmt.Println(err)
-			return
+	if args[3] == "stats" {
+		res, errs := GetStats(paths, format, isLogs, workers)
+		for name, val := range res {
+			fmt.Printf(name + "\t" + strconv.Itoa(val) + "\n")
 		}
+		reportErrors(errs)
+		return
+	}
 
-		for name, val := range res {
+	// Aggregated outputs need all the teams
+	switch args[3] {
+	case "switchins":
+		res, errs := GetTeams(paths, format, isLogs, workers)
+		for name, val := range GetSwitchIns(res) {
 			fmt.Printf(name + "\t" + strconv.Itoa(val) + "\n")
 		}
-	case "teams":
-		res, err := GetTeams(paths, format, isLogs)
-		if err != nil {
-			fmt.Println(err)
-			return
+		reportErrors(errs)
+		return
+	case "luckreport":
+		res, errs := GetTeams(paths, format, isLogs, workers)
+		luck, battles, wins := GetLuckReport(res)
+		for player, l := range luck {
+			fmt.Printf(player + "\t" + strconv.Itoa(battles[player]) + "\t" + strconv.Itoa(wins[player]) + "\t" + formatLuck(l, "\t") + "\n")
 		}
+		reportErrors(errs)
+		return
+	case "leads":
+		res, errs := GetTeams(paths, format, isLogs, workers)
+		matchups, wins := GetLeadMatchups(res)
+		for name, val := range matchups {
+			fmt.Printf(name + "\t" + strconv.Itoa(val) + "\t" + strconv.Itoa(wins[name]) + "\n")
+		}
+		reportErrors(errs)
+		return
+	}
 
-		for _, team := range res {
-			displayTeam(team)
+	// Teams in showdown's import format, written to files
+	if args[3] == "paste" || args[3] == "pastemerged" {
+		w := &PasteWriter{Dir: pasteDir, Format: format, Merged: args[3] == "pastemerged"}
+		var errs []*ParseError
+		for replay := range ParseReplays(paths, format, isLogs, false, workers) {
+			for _, team := range replay.Teams {
+				err := w.Write(replayID(replay.Path), team)
+				if err != nil {
+					fmt.Println(err)
+					return
+				}
+			}
+			errs = append(errs, replay.Errs...)
 		}
+		reportErrors(errs)
+		return
+	}
+
+	// Other outputs are displayed team by team as soon as they are parsed
+	display, ok := displays[args[3]]
+	if !ok {
+		fmt.Println("unknown output type: " + args[3])
+		return
 	}
+
+	var errs []*ParseError
+	for replay := range ParseReplays(paths, format, isLogs, strings.Contains(format, "monotype"), workers) {
+		for _, team := range replay.Teams {
+			display(team)
+		}
+		errs = append(errs, replay.Errs...)
+	}
+	reportErrors(errs)
 }
 
+// displays are the outputs printing a line or more per team
+var displays = map[string]func(*Team){
+	"teams":     displayTeam,
+	"jsonl":     displayTeamJSON,
+	"hazards":   displayHazardStats,
+	"hazardlog": displayHazards,
+	"weather":   displayConditions,
+	"status":    displayStatuses,
+	"switchlog": displaySwitches,
+	"preview":   displayPreview,
+	"profile":   displayProfile,
+	"timing":    displayTiming,
+	"luck":      displayLuck,
+	"pivots":    displayPivots,
+	"sets":      displaySets,
+}
+
+// reportErrors writes the errors to errorReportFile, the output is on stdout
+// so the summary goes to stderr
+func reportErrors(errs []*ParseError) {
+	if len(errs) == 0 {
+		return
+	}
+
+	err := WriteErrorReport(errorReportFile, errs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "%d errors, see %s\n", len(errs), errorReportFile)
+}
+
+// displayTeam prints the team in the frozen csv layout of 75 columns, the
+// fields added since are only in the jsonl output
 func displayTeam(team *Team) {
 	if team == nil || team.Lead == "" {
 		return
@@ -93,6 +183,10 @@ func displayTeam(team *Team) {
 	output += team.Type + ";"
 	output += leadmon + ";"
 	output += strconv.Itoa(team.BattleLength) + ";"
+	output += team.Archetype + ";"
+	output += strconv.Itoa(team.RatingBefore) + ";"
+	output += strconv.Itoa(team.RatingAfter) + ";"
+	output += team.EndReason + ";"
 	for _, poke := range pokes {
 		for _, p := range team.Pokemons {
 			if poke == p.Name {
@@ -102,13 +196,266 @@ func displayTeam(team *Team) {
 				output += strconv.Itoa(p.Kills) + ";"
 				output += strconv.Itoa(p.Deaths) + ";"
 				output += strconv.Itoa(p.Entrances) + ";"
+				output += formatCounts(p.StatusInflicted) + ";"
+				output += formatCounts(p.StatusReceived) + ";"
 			}
 		}
 	}
 	for i < 6 {
-		output += ";;;;;;"
+		output += strings.Repeat(";", pokemonColumns)
 		i++
 	}
 	output += team.Result
 	fmt.Println(output)
 }
+
+// displayTeamJSON prints the whole team on one line, pokemons are keyed by
+// nickname
+func displayTeamJSON(team *Team) {
+	if team == nil || team.Lead == "" {
+		return
+	}
+
+	b, err := json.Marshal(team)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	fmt.Println(string(b))
+}
+
+// displayHazardStats prints a line for the team (with an empty pokemon) and a
+// line per pokemon: player;pokemon;hazards_set;hazards_removed;damage_dealt;damage_taken;result
+func displayHazardStats(team *Team) {
+	if team == nil || team.Lead == "" {
+		return
+	}
+
+	dealt := map[string]int{}
+	teamSet, teamRemoved, teamDealt, teamTaken := 0, 0, 0, 0
+	for _, h := range team.Hazards {
+		dealt[h.Setter] += h.Damage
+		teamDealt += h.Damage
+	}
+
+	for _, poke := range team.Pokemons {
+		teamSet += poke.HazardsSet
+		teamRemoved += poke.HazardsRemoved
+		teamTaken += poke.HazardDamage
+	}
+
+	fmt.Println(team.Player + ";;" +
+		strconv.Itoa(teamSet) + ";" +
+		strconv.Itoa(teamRemoved) + ";" +
+		strconv.Itoa(teamDealt) + ";" +
+		strconv.Itoa(teamTaken) + ";" +
+		team.Result +
+		metadataColumns(team))
+
+	for _, poke := range team.Pokemons {
+		fmt.Println(team.Player + ";" +
+			poke.Name + ";" +
+			strconv.Itoa(poke.HazardsSet) + ";" +
+			strconv.Itoa(poke.HazardsRemoved) + ";" +
+			strconv.Itoa(dealt[poke.Name]) + ";" +
+			strconv.Itoa(poke.HazardDamage) + ";" +
+			team.Result +
+			metadataColumns(team))
+	}
+}
+
+// displayHazards prints a line per hazard set by the team:
+// player;hazard;setter;layers;turn_set;turns_up;removed_by;removal_move;damage;result
+func displayHazards(team *Team) {
+	if team == nil || team.Lead == "" {
+		return
+	}
+
+	for _, h := range team.Hazards {
+		fmt.Println(team.Player + ";" +
+			h.Name + ";" +
+			h.Setter + ";" +
+			strconv.Itoa(h.Layers) + ";" +
+			strconv.Itoa(h.TurnSet) + ";" +
+			strconv.Itoa(h.TurnsUp(team.BattleLength)) + ";" +
+			h.RemovedBy + ";" +
+			h.RemovalMove + ";" +
+			strconv.Itoa(h.Damage) + ";" +
+			team.Result +
+			metadataColumns(team))
+	}
+}
+
+// displayConditions prints a line per weather or terrain set by the team:
+// player;condition;setter;turn_start;turns_active;kills;opp_kills;benefited;archetype;result
+func displayConditions(team *Team) {
+	if team == nil || team.Lead == "" {
+		return
+	}
+
+	for _, c := range team.Conditions {
+		fmt.Println(team.Player + ";" +
+			c.Name + ";" +
+			c.Setter + ";" +
+			strconv.Itoa(c.TurnStart) + ";" +
+			strconv.Itoa(c.TurnsActive()) + ";" +
+			strconv.Itoa(c.Kills) + ";" +
+			strconv.Itoa(c.OppKills) + ";" +
+			c.Benefited() + ";" +
+			team.Archetype + ";" +
+			team.Result +
+			metadataColumns(team))
+	}
+}
+
+// displayStatuses prints a line per status received by the team:
+// player;target;status;inflicter;cause;turn;turns_active;result
+func displayStatuses(team *Team) {
+	if team == nil || team.Lead == "" {
+		return
+	}
+
+	for _, s := range team.Statuses {
+		fmt.Println(team.Player + ";" +
+			s.Target + ";" +
+			s.Status + ";" +
+			s.Inflicter + ";" +
+			s.Cause + ";" +
+			strconv.Itoa(s.TurnStart) + ";" +
+			strconv.Itoa(s.TurnsActive()) + ";" +
+			team.Result +
+			metadataColumns(team))
+	}
+}
+
+// displaySwitches prints a line per voluntary switch made by the team:
+// player;turn;out;in;opponent;opponent_move;result
+func displaySwitches(team *Team) {
+	if team == nil || team.Lead == "" {
+		return
+	}
+
+	for _, s := range team.Switches {
+		fmt.Println(team.Player + ";" +
+			strconv.Itoa(s.Turn) + ";" +
+			s.Out + ";" +
+			s.In + ";" +
+			s.Opponent + ";" +
+			s.OppMove + ";" +
+			team.Result +
+			metadataColumns(team))
+	}
+}
+
+// displayPreview prints the team preview and the leads of the team:
+// player;preview;brought;leads;opponent_leads;result
+func displayPreview(team *Team) {
+	if team == nil || team.Lead == "" {
+		return
+	}
+
+	fmt.Println(team.Player + ";" +
+		strings.Join(team.Preview, ",") + ";" +
+		strconv.Itoa(team.Brought) + ";" +
+		strings.Join(team.Leads, ",") + ";" +
+		strings.Join(team.OppLeads, ",") + ";" +
+		team.Result +
+		metadataColumns(team))
+}
+
+// displaySets prints a line per pokemon with its set, known lists the fields
+// and moves known from the team but not revealed in the battle:
+// player;pokemon;item;ability;tera_type;nature;evs;move1;move2;move3;move4;known
+func displaySets(team *Team) {
+	if team == nil || team.Lead == "" {
+		return
+	}
+
+	for _, poke := range team.Pokemons {
+		fmt.Println(team.Player + ";" +
+			poke.Name + ";" +
+			poke.Item + ";" +
+			poke.Ability + ";" +
+			poke.TeraType + ";" +
+			poke.Nature + ";" +
+			poke.EVs + ";" +
+			strings.Join(poke.Moves, ";") + ";" +
+			strings.Join(KnownFields(poke), ",") +
+			metadataColumns(team))
+	}
+}
+
+// displayProfile prints the type profile of the team, the counts are numbers
+// of pokemons: player;types;weaknesses;resistances;immunities;result
+func displayProfile(team *Team) {
+	if team == nil || team.Lead == "" || team.Profile == nil {
+		return
+	}
+
+	fmt.Println(team.Player + ";" +
+		formatCounts(team.Profile.Types) + ";" +
+		formatCounts(team.Profile.Weaknesses) + ";" +
+		formatCounts(team.Profile.Resistances) + ";" +
+		formatCounts(team.Profile.Immunities) + ";" +
+		team.Result +
+		metadataColumns(team))
+}
+
+// displayTiming prints the wall-clock time of the battle, in seconds:
+// player;start;duration;battle_length;turn_times;switch_time;timer_left;result
+func displayTiming(team *Team) {
+	if team == nil || team.Lead == "" {
+		return
+	}
+
+	turnTimes := make([]string, len(team.TurnTimes))
+	for i, t := range team.TurnTimes {
+		turnTimes[i] = strconv.Itoa(t)
+	}
+
+	fmt.Println(team.Player + ";" +
+		strconv.FormatInt(team.Start, 10) + ";" +
+		strconv.Itoa(team.Duration) + ";" +
+		strconv.Itoa(team.BattleLength) + ";" +
+		strings.Join(turnTimes, ",") + ";" +
+		strconv.Itoa(team.SwitchTime) + ";" +
+		strconv.Itoa(team.TimerLeft) + ";" +
+		team.Result +
+		metadataColumns(team))
+}
+
+// displayLuck prints the random events of the battle for and against the
+// team: player;crits;crits_taken;misses;opp_misses;secondaries;secondaries_taken;
+// full_paras;opp_full_paras;sleep_turns;freeze_turns;hax_kos;hax_kos_taken;score;result
+func displayLuck(team *Team) {
+	if team == nil || team.Lead == "" || team.Luck == nil {
+		return
+	}
+
+	fmt.Println(team.Player + ";" +
+		formatLuck(team.Luck, ";") + ";" +
+		team.Result +
+		metadataColumns(team))
+}
+
+// displayPivots prints how the team switched and its momentum by turn:
+// player;hard_switches;pivot_switches;pivot_moves;drags;dragged;double_switches;momentum;archetype;result
+func displayPivots(team *Team) {
+	if team == nil || team.Lead == "" {
+		return
+	}
+
+	p := team.Pivoting
+	fmt.Println(team.Player + ";" +
+		strconv.Itoa(p.HardSwitches) + ";" +
+		strconv.Itoa(p.PivotSwitches) + ";" +
+		strconv.Itoa(p.PivotMoves) + ";" +
+		strconv.Itoa(p.Drags) + ";" +
+		strconv.Itoa(p.Dragged) + ";" +
+		strconv.Itoa(p.DoubleSwitches) + ";" +
+		formatMomentum(team.Momentum) + ";" +
+		team.Archetype + ";" +
+		team.Result +
+		metadataColumns(team))
+}
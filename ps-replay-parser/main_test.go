@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nailec/ps-usage-stats/pokedex"
+)
+
+// captureOutput returns what f prints on the standard output
+func captureOutput(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	f()
+	os.Stdout = stdout
+	w.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestDisplayTeam(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	// p2 only brought 2 pokemons, the missing ones are empty columns
+	for _, id := range []string{"p1", "p2"} {
+		output := captureOutput(t, func() { displayTeam(teams[id]) })
+		if got := len(strings.Split(strings.TrimSpace(output), ";")); got != 75 {
+			t.Errorf("%s: got %d columns, want 75", id, got)
+		}
+	}
+}
+
+func TestDisplayTeamJSON(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	output := captureOutput(t, func() { displayTeamJSON(teams["p1"]) })
+	if strings.Count(output, "\n") != 1 {
+		t.Fatalf("got %q, want a single line", output)
+	}
+
+	var team Team
+	if err := json.Unmarshal([]byte(output), &team); err != nil {
+		t.Fatal(err)
+	}
+
+	if team.Player != "Alice" || team.EndReason != "forfeit" || len(team.Hazards) != 2 {
+		t.Errorf("got %s %s %d hazards", team.Player, team.EndReason, len(team.Hazards))
+	}
+	if ferro := team.Pokemons["Ferrothorn"]; ferro == nil || ferro.HazardsSet != 2 {
+		t.Errorf("got Ferrothorn %+v", ferro)
+	}
+
+	for _, key := range []string{`"end_reason":`, `"hazard_damage":`, `"opp_leads":`} {
+		if !strings.Contains(output, key) {
+			t.Errorf("%s is missing", key)
+		}
+	}
+	if strings.Contains(output, `"hp"`) {
+		t.Errorf("unexported fields are exported")
+	}
+}
+
+func TestDisplayProfile(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+	team := teams["p2"]
+	team.Profile = &pokedex.Profile{
+		Types:       map[string]int{"Ground": 1, "Flying": 1},
+		Weaknesses:  map[string]int{"Ice": 1, "Water": 1},
+		Resistances: map[string]int{},
+		Immunities:  map[string]int{"Electric": 1, "Ground": 1},
+	}
+
+	want := "Bob;Flying:1,Ground:1;Ice:1,Water:1;;Electric:1,Ground:1;L;8;[Gen 8] OU;Sleep Clause Mod,Species Clause\n"
+	if got := captureOutput(t, func() { displayProfile(team) }); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
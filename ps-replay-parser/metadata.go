@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nailec/ps-usage-stats/pokedex"
+)
+
+// Metadata is the format of the battle, the same for both teams
+type Metadata struct {
+	GameType string   `json:"game_type"` // singles, doubles, ... empty if the log does not tell
+	Gen      int      `json:"gen"`       // From the |gen| line, or the format
+	Tier     string   `json:"tier"`      // As displayed: [Gen 8] OU
+	Rules    []string `json:"rules"`     // Clauses and mods: Species Clause, Sleep Clause Mod...
+}
+
+// dex returns the pokedex of the generation of the battle, from the |gen|
+// line or else the tier, the latest one if the log tells neither
+func (m Metadata) dex() *pokedex.Dex {
+	if m.Gen != 0 {
+		return pokedex.ForGen(m.Gen)
+	}
+	if m.Tier != "" {
+		return pokedex.ForGen(pokedex.FormatGen(pokedex.ToID(m.Tier)))
+	}
+
+	return pokedex.Load()
+}
+
+// |gametype|doubles
+// returns the game type
+func getGameType(line string) string {
+	expected := regexp.MustCompile(`^\|gametype\|([^\|]*)`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return ""
+	}
+	return res[1]
+}
+
+// |gen|8
+// returns the generation, 0 if it cannot be read
+func getGen(line string) int {
+	expected := regexp.MustCompile(`^\|gen\|(\d+)`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return 0
+	}
+
+	gen, _ := strconv.Atoi(res[1])
+	return gen
+}
+
+// |tier|[Gen 8] OU
+// returns the tier
+func getTier(line string) string {
+	expected := regexp.MustCompile(`^\|tier\|([^\|]*)`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return ""
+	}
+	return res[1]
+}
+
+// |rule|Species Clause: Limit one of each Pokémon
+// returns the name of the rule
+func getRule(line string) string {
+	expected := regexp.MustCompile(`^\|rule\|([^:\|]*)`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(res[1])
+}
+
+// checkTier returns an error if the tier of the battle is not the format:
+// forum threads mix generations, a battle of another generation is skipped
+// (reason gen) while another tier of the same generation (a tiebreak in UU)
+// is kept with a warning (reason tier)
+func checkTier(meta Metadata, format string) *ParseError {
+	if meta.Tier == "" || format == "" || pokedex.ToID(meta.Tier) == pokedex.ToID(format) {
+		return nil
+	}
+
+	if meta.Gen != pokedex.FormatGen(format) {
+		return &ParseError{Reason: "gen", Err: fmt.Errorf("gen %d battle (%s) is not %s", meta.Gen, meta.Tier, format)}
+	}
+
+	return &ParseError{Reason: "tier", Err: fmt.Errorf("%s battle is not %s", meta.Tier, format)}
+}
+
+// metadataColumns returns the columns added after the result to the csv
+// outputs: ;gen;tier;rules
+func metadataColumns(team *Team) string {
+	return ";" + strconv.Itoa(team.Gen) + ";" + team.Tier + ";" + strings.Join(team.Rules, ",")
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetadata(t *testing.T) {
+	teams := parseFixture(t, "gen9vgc2024regg-1.log")
+
+	want := Metadata{
+		GameType: "doubles",
+		Gen:      9,
+		Tier:     "[Gen 9] VGC 2024 Reg G",
+		Rules:    []string{"Species Clause"},
+	}
+	for _, id := range []string{"p1", "p2"} {
+		if got := teams[id].Metadata; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", id, got, want)
+		}
+	}
+}
+
+func TestMetadataDex(t *testing.T) {
+	// Clefable was Normal before gen 6
+	tests := []struct {
+		meta Metadata
+		want string
+	}{
+		{Metadata{Gen: 5, Tier: "[Gen 8] OU"}, "Normal"},
+		{Metadata{Tier: "[Gen 5] OU"}, "Normal"},
+		{Metadata{}, "Fairy"},
+	}
+	for _, tt := range tests {
+		if types, _ := tt.meta.dex().Types("Clefable"); types[0] != tt.want {
+			t.Errorf("%+v: got %q, want %s", tt.meta, types, tt.want)
+		}
+	}
+}
+
+func TestCheckTier(t *testing.T) {
+	meta := Metadata{Gen: 8, Tier: "[Gen 8] OU"}
+	tests := map[string]string{
+		"gen8ou": "",
+		"":       "",
+		"gen8uu": "tier",
+		"gen4ou": "gen",
+	}
+	for format, want := range tests {
+		got := ""
+		if err := checkTier(meta, format); err != nil {
+			got = err.Reason
+		}
+		if got != want {
+			t.Errorf("%s: got %q, want %q", format, got, want)
+		}
+	}
+
+	if err := checkTier(Metadata{}, "gen8ou"); err != nil {
+		t.Errorf("got %v for a log without tier", err)
+	}
+}
+
+func TestParseReplaysTier(t *testing.T) {
+	tests := []struct {
+		format string
+		teams  int
+		reason string
+	}{
+		{"gen8uu", 2, "tier"},
+		{"gen4ou", 0, "gen"},
+	}
+	for _, tt := range tests {
+		replay := <-ParseReplays([]string{"testdata/gen8ou-1.log"}, tt.format, true, false, 1)
+		if len(replay.Teams) != tt.teams || len(replay.Errs) != 1 || replay.Errs[0].Reason != tt.reason {
+			t.Errorf("%s: got %d teams and errors %v, want %d teams and a %s error", tt.format, len(replay.Teams), replay.Errs, tt.teams, tt.reason)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import "unicode"
+
+// packedNames are the items, abilities and moves whose name cannot be found
+// back from the packed one by adding the spaces (WillOWisp), by ID
+var packedNames = map[string]string{
+	// Moves
+	"alloutpummeling":      "All-Out Pummeling",
+	"babydolleyes":         "Baby-Doll Eyes",
+	"doubleedge":           "Double-Edge",
+	"forestscurse":         "Forest's Curse",
+	"freezedry":            "Freeze-Dry",
+	"kingsshield":          "King's Shield",
+	"landswrath":           "Land's Wrath",
+	"letssnuggleforever":   "Let's Snuggle Forever",
+	"lightofruin":          "Light of Ruin",
+	"lightthatburnsthesky": "Light That Burns the Sky",
+	"lockon":               "Lock-On",
+	"mudslap":              "Mud-Slap",
+	"multiattack":          "Multi-Attack",
+	"naturesmadness":       "Nature's Madness",
+	"neverendingnightmare": "Never-Ending Nightmare",
+	"poweruppunch":         "Power-Up Punch",
+	"roaroftime":           "Roar of Time",
+	"savagespinout":        "Savage Spin-Out",
+	"selfdestruct":         "Self-Destruct",
+	"softboiled":           "Soft-Boiled",
+	"topsyturvy":           "Topsy-Turvy",
+	"trickortreat":         "Trick-or-Treat",
+	"uturn":                "U-turn",
+	"vcreate":              "V-create",
+	"wakeupslap":           "Wake-Up Slap",
+	"willowisp":            "Will-O-Wisp",
+	"xscissor":             "X-Scissor",
+
+	// Items
+	"heavydutyboots": "Heavy-Duty Boots",
+	"kingsrock":      "King's Rock",
+	"nevermeltice":   "Never-Melt Ice",
+	"upgrade":        "Up-Grade",
+
+	// Abilities
+	"asoneglastrier":          "As One (Glastrier)",
+	"asonespectrier":          "As One (Spectrier)",
+	"beadsofruin":             "Beads of Ruin",
+	"dragonsmaw":              "Dragon's Maw",
+	"embodyaspectcornerstone": "Embody Aspect (Cornerstone)",
+	"embodyaspecthearthflame": "Embody Aspect (Hearthflame)",
+	"embodyaspectteal":        "Embody Aspect (Teal)",
+	"embodyaspectwellspring":  "Embody Aspect (Wellspring)",
+	"goodasgold":              "Good as Gold",
+	"mindseye":                "Mind's Eye",
+	"powerofalchemy":          "Power of Alchemy",
+	"rkssystem":               "RKS System",
+	"soulheart":               "Soul-Heart",
+	"swordofruin":             "Sword of Ruin",
+	"tabletsofruin":           "Tablets of Ruin",
+	"vesselofruin":            "Vessel of Ruin",
+	"wellbakedbody":           "Well-Baked Body",
+	"zerotohero":              "Zero to Hero",
+}
+
+// unpackName returns the name of a packed item, ability or move as showdown
+// writes it in the battle: ShadowBall -> Shadow Ball, HeavyDutyBoots ->
+// Heavy-Duty Boots. Names which are not packed are returned as is
+func unpackName(packed string) string {
+	if name, ok := packedNames[toID(packed)]; ok {
+		return name
+	}
+
+	res := ""
+	var prev rune
+	for _, r := range packed {
+		if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)) ||
+			unicode.IsDigit(r) && unicode.IsLetter(prev) {
+			res += " "
+		}
+		res += string(r)
+		prev = r
+	}
+
+	return res
+}
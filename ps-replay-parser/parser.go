@@ -1,35 +1,83 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"regexp"
-	"runtime/debug"
+	"sort"
 	"strings"
 
+	"github.com/nailec/ps-usage-stats/pokedex"
 	"github.com/pkg/errors"
 )
 
 type Team struct {
-	Pokemons       map[string]*Pokemon // Key is Nickname
-	Lead           string
-	Result         string
-	Player         string
-	Type           string
-	DynamaxPokemon string
-	DynamaxTurn    int
-	BattleLength   int
+	Pokemons  map[string]*Pokemon `json:"pokemons"` // Key is Nickname
+	Lead      string              `json:"lead"`
+	Result    string              `json:"result"`     // W, L, T for a tie or empty if incomplete
+	EndReason string              `json:"end_reason"` // KO, forfeit, timer, tie or incomplete
+	Player    string              `json:"player"`
+	Metadata
+	Type           string           `json:"type"`  // Declared type of a monotype team
+	Types          []string         `json:"types"` // Types shared by the pokemons
+	Profile        *pokedex.Profile `json:"profile"`
+	DynamaxPokemon string           `json:"dynamax_pokemon"`
+	DynamaxTurn    int              `json:"dynamax_turn"`
+	BattleLength   int              `json:"battle_length"`
+	Timing
+	Luck         *Luck             `json:"luck"`
+	Hazards      []*Hazard         `json:"hazards"`    // Hazards set on the opponent's side
+	Conditions   []*FieldCondition `json:"conditions"` // Weathers and terrains set by the team
+	Archetype    string            `json:"archetype"`  // Rain, Sun+Electric, ...
+	Statuses     []*StatusEvent    `json:"statuses"`   // Statuses received by the team's pokemons
+	Switches     []*Switch         `json:"switches"`   // Voluntary switches
+	Pivoting     Pivoting          `json:"pivoting"`
+	Momentum     []int             `json:"momentum"` // Strength over the opponent's at the end of each turn, 0 is the leads
+	Preview      []string          `json:"preview"`  // Names in team preview order
+	Brought      int               `json:"brought"`  // Pokemons brought to the battle
+	Leads        []string          `json:"leads"`    // Names of the leads
+	OppLeads     []string          `json:"opp_leads"`
+	RatingBefore int               `json:"rating_before"` // 0 if unrated
+	RatingAfter  int               `json:"rating_after"`
+
+	slots     []*Pokemon    // By team preview order, see roster.go
+	previewed bool          // Whether the slots come from team preview
+	warnings  []*ParseError // Unresolved pokemons and movesets, the teams are kept
 }
 
 type Pokemon struct {
-	Name      string
-	Moves     []string
-	Item      string
-	Kills     int
-	Deaths    int // only 0 or 1
-	Entrances int
+	Name      string   `json:"name"`
+	Nickname  string   `json:"nickname"` // "" if not seen in the battle
+	Slot      int      `json:"slot"`     // Index in team preview, or of appearance without preview
+	Moves     []string `json:"moves"`
+	Item      string   `json:"item"`
+	Kills     int      `json:"kills"`
+	Deaths    int      `json:"deaths"` // only 0 or 1
+	Entrances int      `json:"entrances"`
+
+	TurnsActive int `json:"turns_active"` // Turns started on the field
+	MovesUsed   int `json:"moves_used"`   // A move calling another (Copycat, Metronome...) counts once
+
+	HazardsSet     int `json:"hazards_set"`
+	HazardsRemoved int `json:"hazards_removed"`
+	HazardDamage   int `json:"hazard_damage"` // HP percentage lost to hazards
+
+	StatusInflicted map[string]int `json:"status_inflicted"` // Statuses inflicted to opponents by status
+	StatusReceived  map[string]int `json:"status_received"`
+
+	// Only revealed in some battles or known from the team
+	Ability  string            `json:"ability"`
+	TeraType string            `json:"tera_type"`
+	Nature   string            `json:"nature"`
+	EVs      string            `json:"evs"` // 252 HP / 4 Def / 252 SpD
+	IVs      string            `json:"ivs"`
+	Origins  map[string]string `json:"origins"` // Revealed or Known by field (item, ability, tera, nature, evs, ivs) and by move
+
+	hp       int
+	status   *StatusEvent
+	borrowed map[string]bool // Moves copied with Mimic or Sketch, not part of the set
+	hidden   bool            // Forme hidden by team preview (Silvally-*) and not revealed yet
 }
 
 func GetURLsFromFile(file, format string) ([]string, error) {
@@ -60,177 +108,150 @@ func GetURLsFromFile(file, format string) ([]string, error) {
 	return urls[:i], nil
 }
 
-func GetTeams(paths []string, format string, isLogs bool) ([]*Team, error) {
-	allTeams := make([]*Team, 2*len(paths))
-	teams := make(map[string]*Team, 2)
-	i := 0
-	var err error
-	var errs []error
-	for _, path := range paths {
-		if isLogs {
-			teams, err = ParsePokemonsFromFile(path)
-		} else {
-			teams, err = ParsePokemonsFromURL(path)
-		}
-		if err != nil {
-			return nil, err
-		}
-
-		for _, team := range teams {
-			if strings.Contains(format, "monotype") {
-				team.Type, err = GetType(team.Pokemons)
-				if err != nil {
-					errs = append(errs, err)
-				}
-			}
-			allTeams[i] = team
-			i++
-		}
+// GetTeams returns the teams of the replays in input order, the replays that
+// could not be parsed are skipped and reported in the errors
+func GetTeams(paths []string, format string, isLogs bool, workers int) ([]*Team, []*ParseError) {
+	allTeams := make([]*Team, 0, 2*len(paths))
+	var errs []*ParseError
+	for replay := range ParseReplays(paths, format, isLogs, strings.Contains(format, "monotype"), workers) {
+		allTeams = append(allTeams, replay.Teams...)
+		errs = append(errs, replay.Errs...)
 	}
 
-	if len(errs) != 0 {
-		for _, err := range errs {
-			fmt.Println(err)
-		}
-		return nil, errors.New("errors occured when trying to find team types")
-	}
-
-	return allTeams, nil
+	return allTeams, errs
 }
 
-func GetStats(paths []string, isLogs bool) (map[string]int, error) {
+// GetStats returns the usage of each pokemon+type combination, the replays
+// that could not be parsed are skipped and reported in the errors
+func GetStats(paths []string, format string, isLogs bool, workers int) (map[string]int, []*ParseError) {
 	stats := map[string]int{}
-	teams := make(map[string]*Team, 2)
-	var err error
-	var teamType string
-	for _, path := range paths {
-		if isLogs {
-			teams, err = ParsePokemonsFromFile(path)
-		} else {
-			teams, err = ParsePokemonsFromURL(path)
-		}
-		if err != nil {
-			return nil, err
-		}
-
-		for _, team := range teams {
-			teamType, err = GetType(team.Pokemons)
-			if err != nil {
-				return nil, err
-			}
-
+	var errs []*ParseError
+	for replay := range ParseReplays(paths, format, isLogs, true, workers) {
+		errs = append(errs, replay.Errs...)
+		for _, team := range replay.Teams {
 			for _, pokemon := range team.Pokemons {
-				stats[pokemon.Name+"\t"+teamType]++
+				stats[pokemon.Name+"\t"+team.Type]++
 			}
 		}
 	}
 
-	return stats, nil
+	return stats, errs
 }
 
-type pokeList struct {
-	Data []string `json:"data"`
-}
-
-func GetType(team map[string]*Pokemon) (string, error) {
-	types := []string{"bug", "dark", "dragon", "electric",
-		"fairy", "fighting", "fire", "flying", "ghost",
-		"grass", "ground", "ice", "normal", "poison",
-		"psychic", "rock", "steel", "water",
+func parsePath(path string, isLogs bool) (map[string]*Team, *ParseError) {
+	var teams map[string]*Team
+	var err error
+	if isLogs {
+		teams, err = ParsePokemonsFromFile(path)
+	} else {
+		teams, err = ParsePokemonsFromURL(path)
+	}
+	if err != nil {
+		return nil, toParseError(err, "parse", replayID(path))
 	}
 
-	var names []string
-	for _, t := range types {
-		names = make([]string, 6)
-		i := 0
-
-		b, err := ioutil.ReadFile("pokelist/" + t + ".json")
-		if err != nil {
-			return "", errors.Wrap(err, "could not read type file: "+t)
-		}
+	return teams, nil
+}
 
-		var list pokeList
-		err = json.Unmarshal(b, &list)
-		if err != nil {
-			return "", errors.Wrap(err, "could not unmarshal poke list of type: "+t)
+// GetType returns the declared type of a monotype team and all the types
+// shared by its pokemons in this generation, Unknown if they share none
+func GetType(team map[string]*Pokemon, gen int) (string, []string, error) {
+	names := make([]string, 0, len(team))
+	for _, p := range team {
+		// An unrevealed Silvally-* can have the type of any of its formes
+		if p.hidden {
+			names = append(names, p.Name+"-*")
+		} else {
+			names = append(names, p.Name)
 		}
+	}
+	sort.Strings(names)
 
-		typeMatch := true
-		for _, p := range team {
-			names[i] = p.Name
-			i++
-			if strings.HasPrefix(p.Name, "Silvally-") {
-				continue
-			}
-			found := false
-			for _, p2 := range list.Data {
-				if p.Name == p2 {
-					found = true
-					break
-				}
-			}
+	declared, shared, err := pokedex.ForGen(gen).Monotype(names)
+	if err != nil {
+		return "Unknown", nil, err
+	}
 
-			if !found {
-				typeMatch = false
-			}
-		}
+	return declared, shared, nil
+}
 
-		if typeMatch {
-			return t, nil
-		}
+// GetProfile returns the type profile of the team in this generation
+func GetProfile(team map[string]*Pokemon, gen int) (*pokedex.Profile, error) {
+	names := make([]string, 0, len(team))
+	for _, p := range team {
+		names = append(names, p.Name)
 	}
+	sort.Strings(names)
 
-	return "Unknown", fmt.Errorf("no type found for team: %+v", names)
+	return pokedex.ForGen(gen).Profile(names)
 }
 
 func ParsePokemonsFromFile(file string) (map[string]*Team, error) {
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Println(file)
-		}
-	}()
-
 	b, err := ioutil.ReadFile(file)
 	if err != nil {
-		return nil, err
+		return nil, &ParseError{Reason: "read", Err: err}
+	}
+
+	if strings.HasSuffix(file, ".log.json") {
+		return ParsePokemonsFromServerLog(b)
+	}
+
+	log, err := ExtractLog(string(b))
+	if err != nil {
+		return nil, &ParseError{Reason: "read", Err: err}
 	}
 
-	return ParsePokemonsFromHtml(string(b))
+	return ParsePokemonsFromHtml(log)
 }
 
 func ParsePokemonsFromURL(url string) (map[string]*Team, error) {
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Println(url)
-			fmt.Println(string(debug.Stack()))
-		}
-	}()
-
 	resp, err := http.Get(url + ".log")
 	if err != nil {
-		return nil, err
+		return nil, &ParseError{Reason: "fetch", Err: err}
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("could not access: %s, code: %d",
-			url, resp.StatusCode)
+		return nil, &ParseError{
+			Reason: "fetch",
+			Err:    fmt.Errorf("could not access: %s, code: %d", url, resp.StatusCode),
+		}
 	}
 
-	defer resp.Body.Close()
 	html, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, &ParseError{Reason: "fetch", Err: err}
 	}
 
 	if strings.Contains(string(html), "Could not connect") {
-		return nil, fmt.Errorf("could not connect to: %s", url)
+		return nil, &ParseError{
+			Reason: "fetch",
+			Err:    fmt.Errorf("could not connect to: %s", url),
+		}
 	}
 
 	return ParsePokemonsFromHtml(string(html))
 }
 
-func ParsePokemonsFromHtml(html string) (map[string]*Team, error) {
-	teams := map[string]*Team{
+// ParsePokemonsFromHtml returns the teams by player ID (p1 and p2) from a raw
+// battle log, see ExtractLog for the other formats. A line that cannot be
+// parsed returns a ParseError
+func ParsePokemonsFromHtml(html string) (teams map[string]*Team, err error) {
+	var lineNum int    // 1-based number of the line being parsed
+	var current string // The line being parsed
+	defer func() {
+		if r := recover(); r != nil {
+			teams = nil
+			err = &ParseError{
+				Reason:  "parse",
+				Line:    lineNum,
+				Content: current,
+				Err:     fmt.Errorf("%v", r),
+			}
+		}
+	}()
+
+	teams = map[string]*Team{
 		"p1": &Team{
 			Result:   "L",
 			Pokemons: map[string]*Pokemon{},
@@ -243,13 +264,74 @@ func ParsePokemonsFromHtml(html string) (map[string]*Team, error) {
 
 	playerIDs := map[string]string{}     // Stores a player ID by name
 	playerCurrent := map[string]string{} // Stores the pokemon on this player's side
+	active := map[string]string{}        // Nicknames on the field by position (p1a, p1b...)
 	turn := 0
 
+	var moverID, moverNick, moverMove string // The last pokemon who used a move this turn, until a switch
+	justSwitched := map[string]string{}      // The pokemon who switched in and did not move yet
+	disguises := map[string]*disguise{}      // What the active pokemons did by position, in case of Illusion
+	transformed := map[string]string{}       // The active pokemons who transformed by position, their moves are not their own
+	var weather, terrain *FieldCondition
+	var weatherSide, terrainSide string
+
+	ended := false                       // only the ratings are interesting after we know who won
+	sheets := map[string][]*PokemonSet{} // Open team sheets by player ID
+	endReason := ""
+	var meta Metadata
+	dex := meta.dex() // Species are named as in the generation of the battle
+	clock := newBattleClock()
+	luck := newLuckTracker()
+	timerLeft := map[string]int{"p1": -1, "p2": -1} // By player ID
+
+	// Warnings do not skip the replay, they are reported with the errors
+	warn := func(pID, reason, msg string) {
+		if team, ok := teams[pID]; ok {
+			team.warnings = append(team.warnings, &ParseError{
+				Reason:  reason,
+				Line:    lineNum,
+				Content: current,
+				Err:     errors.New(msg),
+			})
+		}
+	}
+
 	lines := strings.Split(html, "\n")
 	for i, line := range lines {
+		lineNum, current = i+1, line
+		if ended {
+			if strings.HasPrefix(line, "|raw|") {
+				player, before, after := getRatingChange(line)
+				if pID, ok := playerIDs[player]; ok {
+					teams[pID].RatingBefore = before
+					teams[pID].RatingAfter = after
+				}
+			}
+			continue
+		}
+
+		luck.read(line, turn)
+
 		// Init turn
 		if strings.HasPrefix(line, "|turn|") {
+			recordMomentum(teams)
+			countTurnsActive(teams, active, disguises)
 			turn++
+			moverID, moverNick, moverMove = "", "", "" // A failed pivot move is not followed by its switch
+			continue
+		}
+
+		if strings.HasPrefix(line, "|t:|") {
+			if ts := getTimestamp(line); ts != 0 {
+				clock.stamp(ts, turn)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "|inactive|") {
+			player, left, ok := getTimerLeft(line)
+			if pID, known := playerIDs[player]; ok && known {
+				timerLeft[pID] = left
+			}
 			continue
 		}
 
@@ -258,6 +340,27 @@ func ParsePokemonsFromHtml(html string) (map[string]*Team, error) {
 			split := strings.Split(line, "|")
 			playerIDs[split[3]] = split[2]
 			teams[split[2]].Player = split[3]
+			teams[split[2]].RatingBefore = getPlayerRating(line)
+			continue
+		}
+
+		// Init format
+		if strings.HasPrefix(line, "|gametype|") {
+			meta.GameType = getGameType(line)
+			continue
+		}
+		if strings.HasPrefix(line, "|gen|") {
+			meta.Gen = getGen(line)
+			dex = meta.dex()
+			continue
+		}
+		if strings.HasPrefix(line, "|tier|") {
+			meta.Tier = getTier(line)
+			dex = meta.dex()
+			continue
+		}
+		if strings.HasPrefix(line, "|rule|") {
+			meta.Rules = append(meta.Rules, getRule(line))
 			continue
 		}
 
@@ -265,29 +368,40 @@ func ParsePokemonsFromHtml(html string) (map[string]*Team, error) {
 		if strings.HasPrefix(line, "|poke|") {
 			split := strings.Split(line, "|")
 			p := split[2]
-			poke := strings.Split(split[3], ",")[0]
-			poke = cutName(poke)
+			raw := strings.Split(split[3], ",")[0]
+			poke := dex.Normalize(raw)
+			teams[p].Preview = append(teams[p].Preview, poke)
 
 			if poke == "Greninja" {
 				nn := GetNickname(html, p, poke)
 				if checkAshGreninja(html, p, nn) {
-					teams[p].Pokemons[poke] = &Pokemon{
-						Name:  "Greninja-Ash",
-						Moves: make([]string, 4),
-					}
-					continue
+					poke = "Greninja-Ash"
+					teams[p].Preview[len(teams[p].Preview)-1] = poke
 				}
 			}
 
-			// Pokemon is initialized with its base name as nickname
-			teams[p].Pokemons[poke] = &Pokemon{
-				Name:  poke,
-				Moves: make([]string, 4),
+			// The nickname is bound to the slot on the first switch
+			addSlot(teams[p], poke).hidden = strings.HasSuffix(raw, "-*")
+			teams[p].previewed = true
+			continue
+		}
+
+		// Open team sheets, merged at the end to know what was revealed
+		if strings.HasPrefix(line, "|showteam|") {
+			pID, sets := getShowTeamInfo(line)
+			sheets[pID] = sets
+			continue
+		}
+
+		// |teampreview|4 when only some pokemons are brought (VGC)
+		if strings.HasPrefix(line, "|teampreview") {
+			for _, team := range teams {
+				team.Brought = getTeamPreviewInfo(line)
 			}
 			continue
 		}
 
-		// Init leads
+		// Leads are initialized on their switch
 		if strings.HasPrefix(line, "|start") {
 			if strings.HasSuffix(line, "Dynamax") {
 				pID, pokeNick := getDynamaxInfo(line)
@@ -296,25 +410,6 @@ func ParsePokemonsFromHtml(html string) (map[string]*Team, error) {
 				continue
 			}
 
-			pID, pokeNick, pokeName := getPoke(lines[i+1])
-			pokeName = cutName(pokeName)
-			teams[pID].Lead = pokeNick
-			updatePlayerPoke(teams[pID].Pokemons, pokeNick, pokeName)
-			teams[pID].Pokemons[pokeNick].Entrances++
-			if pokeNick != pokeName {
-				delete(teams[pID].Pokemons, pokeName)
-			}
-
-			pID, pokeNick, pokeName = getPoke(lines[i+2])
-			pokeName = cutName(pokeName)
-			teams[pID].Lead = pokeNick
-			updatePlayerPoke(teams[pID].Pokemons, pokeNick, pokeName)
-			teams[pID].Pokemons[pokeNick].Entrances++
-			if pokeNick != pokeName {
-				delete(teams[pID].Pokemons, pokeName)
-			}
-
-			i += 2
 			continue
 		}
 
@@ -329,55 +424,199 @@ func ParsePokemonsFromHtml(html string) (map[string]*Team, error) {
 		// Item detection
 		if strings.Contains(line, "[from] item: ") {
 			pID, pokeNick, item := getItem(line)
+			if pID == "" {
+				return nil, &ParseError{
+					Reason:  "parse",
+					Line:    lineNum,
+					Content: current,
+					Err:     errors.New("cannot read the item"),
+				}
+			}
 			teams[pID].Pokemons[pokeNick].Item = item
 		}
 
+		// Ability detection
+		if strings.HasPrefix(line, "|-ability|") || strings.Contains(line, "[from] ability: ") {
+			pID, pokeNick, ability := getAbility(line)
+			if pID != "" && !isTransformed(transformed, pID, pokeNick) {
+				if poke, ok := teams[pID].Pokemons[pokeNick]; ok && poke.Ability == "" {
+					poke.Ability = ability
+				}
+			}
+		}
+
+		if strings.HasPrefix(line, "|-terastallize|") {
+			pID, pokeNick, tera := getTeraInfo(line)
+			if pID == "" {
+				continue
+			}
+			if poke, ok := teams[pID].Pokemons[pokeNick]; ok {
+				poke.TeraType = tera
+			}
+			continue
+		}
+
 		if strings.HasPrefix(line, "|-enditem") {
 			pID, pokeNick, item := getItemFromEndItem(line)
 			teams[pID].Pokemons[pokeNick].Item = item
 			continue
 		}
 
+		// |-message|Bob forfeited.
+		if strings.HasPrefix(line, "|-message|") {
+			if strings.HasSuffix(line, " forfeited.") {
+				endReason = "forfeit"
+			}
+			if strings.HasSuffix(line, " lost due to inactivity.") {
+				endReason = "timer"
+			}
+			continue
+		}
+
 		// Handle end of battle result
 		if strings.HasPrefix(line, "|win") {
+			recordMomentum(teams)
 			split := strings.Split(line, "|")
 			teams[playerIDs[split[2]]].Result = "W"
+			if endReason == "" {
+				endReason = "KO"
+			}
 			for _, team := range teams {
 				team.BattleLength = turn
+				team.EndReason = endReason
 			}
-			break // nothing is interesting after we know who won
+			ended = true
+			continue
+		}
+
+		if line == "|tie" || strings.HasPrefix(line, "|tie|") {
+			recordMomentum(teams)
+			for _, team := range teams {
+				team.Result = "T"
+				team.BattleLength = turn
+				team.EndReason = "tie"
+			}
+			ended = true
+			continue
 		}
 
 		// Update nickname and details on forms (silvally, pumpkaboo, ...)
 		if strings.HasPrefix(line, "|switch") || strings.HasPrefix(line, "|drag") {
 			pID, pokeNick, pokeName := getPoke(line)
+			pos := getPosition(line)
+			outgoing := active[pos]                         // In doubles the other slot may have switched last
+			voluntary := strings.HasPrefix(line, "|switch") // drags are forced
+			pivot := ""
+			if voluntary && moverID == pID && moverNick == outgoing && stringInSlice(moverMove, pivotMoves) {
+				pivot = moverMove
+			}
+			moverID, moverNick, moverMove = "", "", ""
 			playerCurrent[pID] = pokeNick
-			if _, ok := teams[pID].Pokemons[pokeNick]; ok {
-				teams[pID].Pokemons[pokeNick].Entrances++
-				continue // already checked ^^
+			active[pos] = pokeNick
+			justSwitched[pID] = pokeNick
+			disguises[pos] = &disguise{nick: pokeNick, lead: turn == 0}
+			delete(transformed, pos)
+			if _, ok := resolvePoke(teams[pID], dex, pokeNick, pokeName); !ok {
+				warn(pID, "identity", pokeNick+" ("+pokeName+") is not in team preview")
 			}
 
-			pokeName = cutName(pokeName)
-			updatePlayerPoke(teams[pID].Pokemons, pokeNick, pokeName)
 			teams[pID].Pokemons[pokeNick].Entrances++
+			teams[pID].Pokemons[pokeNick].hp = getSwitchHP(line)
+
+			// Every pokemon sent before the first turn is a lead (2 in doubles)
+			if turn == 0 {
+				if teams[pID].Lead == "" {
+					teams[pID].Lead = pokeNick
+				}
+				teams[pID].Leads = append(teams[pID].Leads, teams[pID].Pokemons[pokeNick].Name)
+				continue
+			}
+
+			opp := getOpp(pID)
+			if voluntary {
+				recordSwitch(teams[pID], teams[opp], outgoing, pokeNick, facing(active, pos), pivot, turn)
+			} else {
+				teams[pID].Pivoting.Dragged++
+				teams[opp].Pivoting.Drags++
+			}
+			continue
+		}
+
+		// Illusion is broken, what the disguise did was done by Zoroark
+		if strings.HasPrefix(line, "|replace|") {
+			pID, pokeNick, pokeName := getReplaceInfo(line)
+			if pID == "" {
+				continue
+			}
+			if _, ok := resolvePoke(teams[pID], dex, pokeNick, pokeName); !ok {
+				warn(pID, "identity", pokeNick+" ("+pokeName+") is not in team preview")
+			}
+			pos := getPosition(line)
+			if d, ok := disguises[pos]; ok {
+				breakIllusion(teams[pID], d, pokeNick)
+				if justSwitched[pID] == d.nick {
+					justSwitched[pID] = pokeNick
+				}
+				if moverID == pID && moverNick == d.nick {
+					moverNick = pokeNick
+				}
+			}
 			playerCurrent[pID] = pokeNick
-			if pokeNick != pokeName {
-				delete(teams[pID].Pokemons, pokeName)
+			active[pos] = pokeNick
+			disguises[pos] = &disguise{nick: pokeNick}
+			continue
+		}
+
+		// Until it switches out the pokemon uses the moves of its target
+		if strings.HasPrefix(line, "|-transform|") {
+			pID, pokeNick := getTransformInfo(line)
+			if pID != "" {
+				transformed[getPosition(line)] = pokeNick
 			}
 			continue
 		}
 
+		if strings.HasPrefix(line, "|-start|") || strings.HasPrefix(line, "|-activate|") {
+			pID, pokeNick, move := getCopiedMoveInfo(line)
+			if pID != "" {
+				if poke, ok := teams[pID].Pokemons[pokeNick]; ok {
+					if poke.borrowed == nil {
+						poke.borrowed = map[string]bool{}
+					}
+					poke.borrowed[move] = true
+				}
+			}
+		}
+
+		// Aegislash-Blade, Darmanitan-Zen, Morpeko-Hangry... are temporary,
+		// the name is kept and the ability was read above
+		if strings.HasPrefix(line, "|-formechange|") {
+			continue
+		}
+
 		if strings.HasPrefix(line, "|faint") {
 			pID, pokeNick := getFaintInfo(line)
+			delete(active, getPosition(line))
 			opp := getOpp(pID)
-			teams[opp].Pokemons[playerCurrent[opp]].Kills++
+			clock.forced[pID] = true
+			killer := playerCurrent[opp]
+			if moverID == opp {
+				killer = moverNick // In doubles the last active one may not be the attacker
+			}
+			teams[opp].Pokemons[killer].Kills++
+			if d := disguiseOf(disguises, opp, killer); d != nil {
+				d.kills++
+			}
 			teams[pID].Pokemons[pokeNick].Deaths++
+			cureStatus(teams[pID].Pokemons[pokeNick], turn)
+			addConditionKill(weather, weatherSide, opp)
+			addConditionKill(terrain, terrainSide, opp)
 		}
 
 		// Update form detail
 		if strings.HasPrefix(line, "|detailschange") {
 			pID, pokeNick, pokeName := getDetailschange(line)
-			teams[pID].Pokemons[pokeNick].Name = pokeName
+			teams[pID].Pokemons[pokeNick].Name = dex.Normalize(pokeName)
 			continue
 		}
 
@@ -412,19 +651,29 @@ func ParsePokemonsFromHtml(html string) (map[string]*Team, error) {
 			}
 
 			pID, pokeNick, move := getMoveInfo(line)
+			moverID, moverNick = pID, pokeNick
 			if strings.HasPrefix(move, "Z-") {
 				move = strings.TrimPrefix(move, "Z-")
 			}
+			moverMove = move
+			delete(justSwitched, pID)
+			if stringInSlice(move, pivotMoves) {
+				teams[pID].Pivoting.PivotMoves++
+			}
+			teams[pID].Pokemons[pokeNick].MovesUsed++
+			if d := disguiseOf(disguises, pID, pokeNick); d != nil {
+				d.movesUsed++
+			}
+			fillSwitchMove(teams[getOpp(pID)], teams[pID].Pokemons[pokeNick].Name, move, turn)
 			if move == "Struggle" {
 				continue
 			}
 			if strings.HasPrefix(move, "Max ") || strings.HasPrefix(move, "G-Max ") {
 				continue
 			}
-			if teams[pID].Pokemons[pokeNick].Name == "Ditto" {
-				continue
+			if !revealMove(teams[pID], pokeNick, move, isTransformed(transformed, pID, pokeNick), disguiseOf(disguises, pID, pokeNick)) {
+				warn(pID, "moveset", "no room for "+move+" on "+teams[pID].Pokemons[pokeNick].Name)
 			}
-			addMove(teams[pID].Pokemons[pokeNick].Moves, move)
 			continue
 		}
 
@@ -434,10 +683,9 @@ func ParsePokemonsFromHtml(html string) (map[string]*Team, error) {
 			if pID == "" {
 				continue
 			}
-			if teams[pID].Pokemons[pokeNick].Name == "Ditto" {
-				continue
+			if !revealMove(teams[pID], pokeNick, move, isTransformed(transformed, pID, pokeNick), disguiseOf(disguises, pID, pokeNick)) {
+				warn(pID, "moveset", "no room for "+move+" on "+teams[pID].Pokemons[pokeNick].Name)
 			}
-			addMove(teams[pID].Pokemons[pokeNick].Moves, move)
 			continue
 		}
 
@@ -447,13 +695,215 @@ func ParsePokemonsFromHtml(html string) (map[string]*Team, error) {
 			teams[pID].Pokemons[pokeNick].Item = move
 			continue
 		}
+
+		// Hazards, the setter is the opponent who just moved, an ability
+		// (Toxic Debris) leaves it unknown
+		// |-sidestart|p1: Alice|move: Stealth Rock
+		if strings.HasPrefix(line, "|-sidestart") {
+			side, hazard := getSideStartInfo(line)
+			if !isHazard(hazard) {
+				continue
+			}
+			opp := getOpp(side)
+			setter := ""
+			if moverID == opp {
+				setter = moverNick
+			}
+			setHazard(teams[opp], setter, hazard, turn)
+			continue
+		}
+
+		// |-sideend|p1: Alice|Stealth Rock|[from] move: Defog|[of] p2a: Corviknight
+		if strings.HasPrefix(line, "|-sideend") {
+			side, hazard, move, pID, pokeNick := getSideEndInfo(line)
+			if !isHazard(hazard) {
+				continue
+			}
+			if pID == "" {
+				pID = side
+			}
+			removeHazard(teams[getOpp(side)], teams[pID], hazard, pokeNick, move, turn)
+			continue
+		}
+
+		if strings.HasPrefix(line, "|-activate") && strings.Contains(line, "|move: Court Change") {
+			pID, pokeNick := getCourtChangeInfo(line)
+			if pID == "" {
+				continue
+			}
+			courtChange(teams, pID, pokeNick, turn)
+			continue
+		}
+
+		// Track HP to know the damage dealt by hazards
+		if strings.HasPrefix(line, "|-damage") || strings.HasPrefix(line, "|-heal") {
+			pID, pokeNick, hp, from := getHPChange(line)
+			if pID == "" {
+				continue
+			}
+			poke, ok := teams[pID].Pokemons[pokeNick]
+			if !ok {
+				continue
+			}
+			lost := poke.hp - hp
+			poke.hp = hp
+			if !isHazard(from) {
+				continue
+			}
+			poke.HazardDamage += lost
+			if h := activeHazard(teams[getOpp(pID)], from); h != nil {
+				h.Damage += lost
+			}
+			continue
+		}
+
+		// Weather, the setter is the [of] pokemon or the last one who moved
+		// |-weather|RainDance|[from] ability: Drizzle|[of] p1a: Bird
+		if strings.HasPrefix(line, "|-weather") {
+			name, upkeep, pID, pokeNick := getWeatherInfo(line)
+			if upkeep {
+				continue
+			}
+			weather = endCondition(weather, turn)
+			if name == "" || name == "none" {
+				continue
+			}
+			if pID == "" {
+				pID, pokeNick = moverID, moverNick
+			}
+			if pID == "" {
+				continue
+			}
+			weather = startCondition(teams[pID], pokeNick, name, turn)
+			weatherSide = pID
+			continue
+		}
+
+		// |-fieldstart|move: Electric Terrain|[from] ability: Electric Surge|[of] p1a: Tapu Koko
+		if strings.HasPrefix(line, "|-fieldstart") {
+			name, pID, pokeNick := getFieldStartInfo(line)
+			if _, ok := terrainLabels[name]; !ok {
+				continue
+			}
+			terrain = endCondition(terrain, turn)
+			if pID == "" {
+				pID, pokeNick = moverID, moverNick
+			}
+			if pID == "" {
+				continue
+			}
+			terrain = startCondition(teams[pID], pokeNick, name, turn)
+			terrainSide = pID
+			continue
+		}
+
+		if strings.HasPrefix(line, "|-fieldend") {
+			if terrain != nil && terrain.Name == getFieldEndInfo(line) {
+				terrain = endCondition(terrain, turn)
+			}
+			continue
+		}
+
+		// The inflicter is the [of] pokemon, the opponent who just moved or
+		// the setter of toxic spikes
+		// |-status|p2a: Heatran|brn|[from] ability: Flame Body|[of] p1a: Volcarona
+		if strings.HasPrefix(line, "|-status|") {
+			pID, pokeNick, status, from, ofID, ofNick := getStatusInfo(line)
+			if pID == "" {
+				continue
+			}
+			opp := getOpp(pID)
+			tspikes := activeHazard(teams[opp], "Toxic Spikes")
+			switch {
+			case stringInSlice(from, selfCauses):
+				inflictStatus(teams[pID], pokeNick, nil, "", status, from, turn)
+			case ofID != "":
+				inflictStatus(teams[pID], pokeNick, teams[ofID], ofNick, status, from, turn)
+			case from != "":
+				inflictStatus(teams[pID], pokeNick, nil, "", status, from, turn)
+			case justSwitched[pID] == pokeNick && tspikes != nil:
+				inflictStatus(teams[pID], pokeNick, teams[opp], nickByName(teams[opp], tspikes.Setter), status, "Toxic Spikes", turn)
+			case moverID == opp:
+				inflictStatus(teams[pID], pokeNick, teams[opp], moverNick, status, moverMove, turn)
+			default:
+				inflictStatus(teams[pID], pokeNick, nil, "", status, "", turn)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "|-curestatus") {
+			pID, pokeNick := getCureStatusInfo(line)
+			if pID == "" {
+				continue
+			}
+			cureStatus(teams[pID].Pokemons[pokeNick], turn)
+			continue
+		}
+
+		if strings.HasPrefix(line, "|-cureteam") {
+			pID := getCureTeamInfo(line)
+			if pID == "" {
+				continue
+			}
+			for _, poke := range teams[pID].Pokemons {
+				cureStatus(poke, turn)
+			}
+			continue
+		}
+	}
+
+	if teams["p1"].Player == "" || teams["p2"].Player == "" {
+		return nil, &ParseError{
+			Reason: "parse",
+			Err:    errors.New("no players found, not a replay log"),
+		}
+	}
+
+	// The log is truncated or the battle is still going on
+	if !ended {
+		for _, team := range teams {
+			team.Result = ""
+			team.BattleLength = turn
+			team.EndReason = "incomplete"
+		}
+	}
+
+	endCondition(weather, turn)
+	endCondition(terrain, turn)
+	teams["p1"].Metadata = meta
+	teams["p2"].Metadata = meta
+	for _, team := range teams {
+		finishRoster(team)
+	}
+	for pID, sets := range sheets {
+		if team, ok := teams[pID]; ok {
+			MergeSets(team, sets)
+		}
+	}
+	for pID, team := range teams {
+		team.Timing = clock.timing(pID)
+		team.TimerLeft = timerLeft[pID]
+		team.Luck = luck.luck[pID]
+	}
+	countSwitches(teams["p1"], teams["p2"])
+	countSwitches(teams["p2"], teams["p1"])
+	teams["p1"].OppLeads = teams["p2"].Leads
+	teams["p2"].OppLeads = teams["p1"].Leads
+	for _, team := range teams {
+		if team.Brought == 0 {
+			team.Brought = len(team.Preview)
+		}
+		team.Archetype = GetArchetype(team)
+		for _, poke := range team.Pokemons {
+			cureStatus(poke, turn)
+		}
 	}
 
 	return teams, nil
 }
 
 func GetNickname(html, player, poke string) string {
-	expected := regexp.MustCompile(`\|switch\|` + player + `a: ([^\|]*)\|` + poke)
+	expected := regexp.MustCompile(`\|switch\|` + player + `[a-d]: ([^\|]*)\|` + poke)
 
 	res := expected.FindStringSubmatch(html)
 	if len(res) < 2 {
@@ -464,16 +914,16 @@ func GetNickname(html, player, poke string) string {
 }
 
 func checkAshGreninja(html, player, nn string) bool {
-	protean := regexp.MustCompile(`\|-start\|` + player + `a: ` + nn + `\|typechange\|([^\|]*)\|\[from\] (ability: )?Protean`)
+	protean := regexp.MustCompile(`\|-start\|` + player + `[a-d]: ` + nn + `\|typechange\|([^\|]*)\|\[from\] (ability: )?Protean`)
 
-	usedMove := regexp.MustCompile(`\|move\|` + player + `a: ` + nn + `\|([^\|]*)\|`)
+	usedMove := regexp.MustCompile(`\|move\|` + player + `[a-d]: ` + nn + `\|([^\|]*)\|`)
 
 	return strings.Contains(html, "|detailschange|"+player+"a: "+nn+"|"+"Greninja-Ash") || !protean.MatchString(html) && usedMove.MatchString(html)
 }
 
 // returns player, nickname and name
 func getPoke(line string) (string, string, string) {
-	expected := regexp.MustCompile(`\|(switch|drag)\|(p(1|2))a: ([^\|]*)\|([^,|]*)`)
+	expected := regexp.MustCompile(`\|(switch|drag)\|(p(1|2))[a-d]: ([^\|]*)\|([^,|]*)`)
 
 	res := expected.FindStringSubmatch(line)
 	return res[2], res[4], res[5]
@@ -489,7 +939,7 @@ func getOpp(p string) string {
 
 // returns player and nickname
 func getFaintInfo(line string) (string, string) {
-	expected := regexp.MustCompile(`\|faint\|(p(1|2))a: ([^\|]*)$`)
+	expected := regexp.MustCompile(`\|faint\|(p(1|2))[a-d]: ([^\|]*)$`)
 
 	res := expected.FindStringSubmatch(line)
 	return res[1], res[3]
@@ -497,19 +947,21 @@ func getFaintInfo(line string) (string, string) {
 
 // returns player, nickname and item
 func getItemFromEndItem(line string) (string, string, string) {
-	expected := regexp.MustCompile(`\|-enditem\|(p(1|2))a: ([^\|]*)\|([^\|]*)(.*)$`)
+	expected := regexp.MustCompile(`\|-enditem\|(p(1|2))[a-d]: ([^\|]*)\|([^\|]*)(.*)$`)
 
 	res := expected.FindStringSubmatch(line)
 	return res[1], res[3], res[4]
 }
 
-// returns player, nickname and item
+// |-heal|p1a: Ferrothorn|100/100|[from] item: Leftovers
+// |-damage|p2a: Landorus|80/100|[from] item: Rocky Helmet|[of] p1a: Ferrothorn
+// returns player, nickname and item, the pokemon is the [of] one if any
 func getItem(line string) (string, string, string) {
-	expected := regexp.MustCompile(`\|-(damage|heal|status|boost|unboost)\|(p(1|2))a: ([^\|]*)\|([^\[]*)\[from\] item: ([^\|]*)(\|\[of\] (p(1|2))a: ([^\|]*))?$`) // fixme ?
+	expected := regexp.MustCompile(`\|-(damage|heal|status|boost|unboost)\|(p(1|2))[a-d]: ([^\|]*)\|([^\[]*)\[from\] item: ([^\|]*)(\|\[of\] (p(1|2))[a-d]: ([^\|]*))?$`)
 
 	res := expected.FindStringSubmatch(line)
-	if len(res) < 10 {
-		fmt.Println(line, res)
+	if len(res) == 0 {
+		return "", "", ""
 	}
 	if res[10] != "" {
 		return res[8], res[10], res[6]
@@ -520,7 +972,7 @@ func getItem(line string) (string, string, string) {
 
 // returns player, nickname and move
 func getMoveInfo(line string) (string, string, string) {
-	expected := regexp.MustCompile(`\|move\|(p(1|2))a: ([^\|]*)\|([^,|]*)`)
+	expected := regexp.MustCompile(`\|move\|(p(1|2))[a-d]: ([^\|]*)\|([^,|]*)`)
 
 	res := expected.FindStringSubmatch(line)
 	return res[1], res[3], res[4]
@@ -528,7 +980,7 @@ func getMoveInfo(line string) (string, string, string) {
 
 // returns player, nickname
 func getDynamaxInfo(line string) (string, string) {
-	expected := regexp.MustCompile(`\|-start\|(p(1|2))a: ([^\|]*)\|Dynamax`)
+	expected := regexp.MustCompile(`\|-start\|(p(1|2))[a-d]: ([^\|]*)\|Dynamax`)
 
 	res := expected.FindStringSubmatch(line)
 	return res[1], res[3]
@@ -537,7 +989,7 @@ func getDynamaxInfo(line string) (string, string) {
 // |cant|p2a: Clefable|move: Taunt|Stealth Rock
 // returns player, move and name
 func getCantMoveInfo(line string) (string, string, string) {
-	expected := regexp.MustCompile(`\|cant\|(p(1|2))a: ([^\|]*)\|move: ([^,|]*)\|([^\|]*)$`)
+	expected := regexp.MustCompile(`\|cant\|(p(1|2))[a-d]: ([^\|]*)\|move: ([^,|]*)\|([^\|]*)$`)
 
 	res := expected.FindStringSubmatch(line)
 	if len(res) == 0 {
@@ -548,176 +1000,58 @@ func getCantMoveInfo(line string) (string, string, string) {
 
 // returns player, nickname and new form name
 func getDetailschange(line string) (string, string, string) {
-	expected := regexp.MustCompile(`\|detailschange\|(p(1|2))a: ([^\|]*)\|([^,|]*)`)
+	expected := regexp.MustCompile(`\|detailschange\|(p(1|2))[a-d]: ([^\|]*)\|([^,|]*)`)
 
 	res := expected.FindStringSubmatch(line)
-
-	if strings.HasPrefix(res[4], "Mimikyu") {
-		res[4] = "Mimikyu"
-	}
-
-	if strings.HasPrefix(res[4], "Toxtricity") {
-		res[4] = "Toxtricity"
-	}
-
-	if strings.HasPrefix(res[4], "Genesect") {
-		res[4] = "Genesect"
-	}
-
-	if strings.HasPrefix(res[4], "Eiscue") {
-		res[4] = "Eiscue"
-	}
-
-	if strings.HasPrefix(res[4], "Minior") {
-		res[4] = "Minior"
-	}
-
 	return res[1], res[3], res[4]
 }
 
-func updatePlayerPoke(pokes map[string]*Pokemon, nick, newName string) {
-	matched := false
-	for oldName, poke := range pokes {
-		if !namesMatch(newName, oldName) {
-			continue
+// returns the nickname of the pokemon with this name, or the name
+func nickByName(team *Team, name string) string {
+	for nick, poke := range team.Pokemons {
+		if poke.Name == name {
+			return nick
 		}
-
-		matched = true
-		// we already have it in current or another form and it's not an annoying form
-		if newName != "Silvally" && newName != "Gourgeist" && newName != "Pumpkaboo" &&
-			(nick == oldName || nick == newName) {
-			break
-		}
-		poke.Name = newName
-		pokes[nick] = poke
-		delete(pokes, oldName)
-		break
 	}
 
-	if !matched {
-		fmt.Println("WTF is " + newName + ", DPP ?")
-		pokes[nick] = &Pokemon{
-			Name:  newName,
-			Moves: make([]string, 4),
-		}
-	}
+	return name
 }
 
+// namesMatch returns whether the names are formes of the same species
 func namesMatch(a, b string) bool {
-	if a == b {
-		return true
-	}
-
-	as := strings.Split(a, "-")
-	bs := strings.Split(b, "-")
-	return as[0] == bs[0]
-}
-
-func cutName(name string) string {
-	if strings.HasPrefix(name, "Urshifu") {
-		return "Urshifu" // TODO fixme
-	}
-
-	if strings.HasPrefix(name, "Mimikyu") {
-		return "Mimikyu"
-	}
-
-	if strings.HasPrefix(name, "Minior") {
-		return "Minior"
-	}
-
-	//	if strings.HasPrefix(name, "Pumpkaboo") {
-	//		return "Pumpkaboo"
-	//	}
-	//
-	//	if strings.HasPrefix(name, "Gourgeist") {
-	//		return "Gourgeist"
-	//	}
-
-	if strings.HasPrefix(name, "Toxtricity") {
-		return "Toxtricity"
-	}
-
-	if strings.HasPrefix(name, "Genesect") {
-		return "Genesect"
-	}
-
-	if strings.HasPrefix(name, "Eiscue") {
-		return "Eiscue"
-	}
-
-	if strings.HasPrefix(name, "Sawsbuck") {
-		return "Sawsbuck"
-	}
-
-	if strings.HasPrefix(name, "Deerling") {
-		return "Deerling"
-	}
-
-	if strings.HasPrefix(name, "Alcremie") {
-		return "Alcremie"
-	}
-
-	if strings.HasPrefix(name, "Pikachu") {
-		return "Pikachu"
-	}
-
-	if strings.HasPrefix(name, "Vivillon") {
-		return "Vivillon"
-	}
-
-	if strings.HasPrefix(name, "Florges") {
-		return "Florges"
-	}
-
-	if strings.HasPrefix(name, "Flabebe") {
-		return "Flabebe"
-	}
-
-	if strings.HasPrefix(name, "Floette") {
-		return "Floette"
-	}
-
-	if strings.HasPrefix(name, "Furfrou") {
-		return "Furfrou"
-	}
-
-	if name == "Gastrodon-East" {
-		return "Gastrodon"
-	}
-
-	if name == "Shellos-East" {
-		return "Shellos"
-	}
-
-	if name == "Basculin-Blue-Striped" {
-		return "Basculin"
-	}
-
-	if name == "Polteageist-Antique" {
-		return "Polteageist"
-	}
-
-	if name == "Keldeo-Resolute" {
-		return "Keldeo"
-	}
-
-	if strings.HasSuffix(name, "-Totem") {
-		return strings.TrimSuffix(name, "-Totem")
-	}
-
-	return name
+	return pokedex.SameSpecies(a, b)
 }
 
-func addMove(a []string, s string) {
+// addMove returns whether the move was added, false if known or if there is
+// no room left
+func addMove(a []string, s string) bool {
 	for i, v := range a {
 		if s == v {
-			return
+			return false
 		}
 		if v == "" {
 			a[i] = s
-			return
+			return true
 		}
 	}
-	fmt.Println("cannot add: " + s + " to: " + strings.Join(a, ","))
+
+	return false
+}
+
+// revealMove adds the move used to the moveset unless it was copied or used
+// while transformed, returns false if the moveset is already full
+func revealMove(team *Team, nick, move string, transformed bool, d *disguise) bool {
+	poke, ok := team.Pokemons[nick]
+	if !ok || transformed || poke.borrowed[move] {
+		return true
+	}
+
+	if addMove(poke.Moves, move) {
+		if d != nil && d.nick == nick {
+			d.moves = append(d.moves, move)
+		}
+		return true
+	}
+
+	return stringInSlice(move, poke.Moves)
 }
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readFixture returns a replay log of testdata
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	b, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+// parseFixture parses a replay log of testdata
+func parseFixture(t *testing.T, name string) map[string]*Team {
+	t.Helper()
+	return parseLog(t, readFixture(t, name))
+}
+
+func parseLog(t *testing.T, log string) map[string]*Team {
+	t.Helper()
+	teams, err := ParsePokemonsFromHtml(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return teams
+}
+
+func TestParseKills(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	if teams["p1"].Player != "Alice" || teams["p2"].Player != "Bob" {
+		t.Fatalf("players: got %s and %s", teams["p1"].Player, teams["p2"].Player)
+	}
+	if teams["p1"].Result != "W" || teams["p2"].Result != "L" {
+		t.Errorf("results: got %s and %s, want W and L", teams["p1"].Result, teams["p2"].Result)
+	}
+
+	tests := []struct {
+		pID, nick     string
+		kills, deaths int
+	}{
+		{"p1", "Barraskewda", 1, 0},
+		{"p1", "Zapdos", 0, 1},
+		{"p2", "Corviknight", 1, 1},
+	}
+	for _, tt := range tests {
+		poke, ok := teams[tt.pID].Pokemons[tt.nick]
+		if !ok {
+			t.Errorf("%s %s not found", tt.pID, tt.nick)
+			continue
+		}
+		if poke.Kills != tt.kills || poke.Deaths != tt.deaths {
+			t.Errorf("%s %s: got %d kills %d deaths, want %d and %d", tt.pID, tt.nick,
+				poke.Kills, poke.Deaths, tt.kills, tt.deaths)
+		}
+	}
+}
+
+func TestEndReason(t *testing.T) {
+	log := readFixture(t, "gen8ou-1.log")
+	end := strings.Index(log, "|-message|Bob forfeited.")
+
+	tests := []struct {
+		name, log, reason string
+		results           [2]string
+	}{
+		{"forfeit", log, "forfeit", [2]string{"W", "L"}},
+		{"timer", strings.Replace(log, "Bob forfeited.", "Bob lost due to inactivity.", 1), "timer", [2]string{"W", "L"}},
+		{"KO", strings.Replace(log, "|-message|Bob forfeited.\n", "", 1), "KO", [2]string{"W", "L"}},
+		{"tie", log[:end] + "|tie\n", "tie", [2]string{"T", "T"}},
+		{"incomplete", log[:end], "incomplete", [2]string{"", ""}},
+	}
+	for _, tt := range tests {
+		teams := parseLog(t, tt.log)
+		for i, pID := range []string{"p1", "p2"} {
+			team := teams[pID]
+			if team.EndReason != tt.reason || team.Result != tt.results[i] {
+				t.Errorf("%s %s: got %s %q, want %s %q", tt.name, pID, team.EndReason, team.Result, tt.reason, tt.results[i])
+			}
+			if team.BattleLength != 9 {
+				t.Errorf("%s %s: got a length of %d, want 9", tt.name, pID, team.BattleLength)
+			}
+		}
+	}
+}
+
+func TestGetType(t *testing.T) {
+	teams := parseFixture(t, "gen8monotype-1.log")
+
+	// The Silvally-* never revealed can be of any type
+	tests := map[string]string{"p1": "fire", "p2": "water"}
+	for id, want := range tests {
+		got, shared, err := GetType(teams[id].Pokemons, 8)
+		if err != nil || got != want || !reflect.DeepEqual(shared, []string{want}) {
+			t.Errorf("%s: got %s %q, %v, want %s", id, got, shared, err, want)
+		}
+	}
+
+	if got, _, err := GetType(parseFixture(t, "gen8ou-1.log")["p1"].Pokemons, 8); err == nil || got != "Unknown" {
+		t.Errorf("got %s, %v, want Unknown and an error", got, err)
+	}
+
+	// Clefable and Togekiss were not Fairy before gen 6
+	team := map[string]*Pokemon{
+		"Clefable": {Name: "Clefable"},
+		"Togekiss": {Name: "Togekiss"},
+	}
+	for gen, want := range map[int]string{5: "normal", 8: "fairy"} {
+		if got, _, err := GetType(team, gen); err != nil || got != want {
+			t.Errorf("gen %d: got %s, %v, want %s", gen, got, err, want)
+		}
+	}
+}
+
+func TestNormalizedNames(t *testing.T) {
+	log := `|player|p1|Alice|1|
+|player|p2|Bob|2|
+|poke|p1|Mimikyu, M|
+|poke|p1|Gastrodon-East, F|
+|poke|p2|Urshifu-*, M|
+|teampreview
+|start
+|switch|p1a: Mimikyu|Mimikyu, M|100/100
+|switch|p2a: Urshifu|Urshifu-Rapid-Strike, M|100/100
+|turn|1
+|-formechange|p1a: Mimikyu|Mimikyu-Busted
+|switch|p1a: Gastrodon|Gastrodon-East, F|100/100
+|win|Alice
+`
+	teams := parseLog(t, log)
+
+	tests := []struct {
+		pID, nick, name string
+	}{
+		{"p1", "Mimikyu", "Mimikyu"},
+		{"p1", "Gastrodon", "Gastrodon"},
+		{"p2", "Urshifu", "Urshifu-Rapid-Strike"},
+	}
+	for _, tt := range tests {
+		if poke := teams[tt.pID].Pokemons[tt.nick]; poke == nil || poke.Name != tt.name {
+			t.Errorf("%s: got %+v, want %s", tt.nick, poke, tt.name)
+		}
+	}
+}
+
+func TestGetProfile(t *testing.T) {
+	team := map[string]*Pokemon{
+		"Heatran":   {Name: "Heatran"},
+		"Volcarona": {Name: "Volcarona"},
+	}
+
+	p, err := GetProfile(team, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Types["Fire"] != 2 || p.Weaknesses["Water"] != 2 || p.Weaknesses["Rock"] != 1 || p.Immunities["Poison"] != 1 {
+		t.Errorf("got %+v", *p)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// unknownField marks what was not revealed in the battle, showdown ignores it
+// on import
+const unknownField = "???"
+
+// PasteWriter writes the teams in showdown's import format, a file per player
+// and per replay or a file per player with all its teams
+type PasteWriter struct {
+	Dir    string
+	Format string
+	Merged bool
+
+	written map[string]bool // Merged files written by this run, the others are overwritten
+}
+
+// Write writes the team of the replay, each team starts with a header so the
+// files can be imported as a teambuilder backup: === [gen8ou] replay ===
+func (w *PasteWriter) Write(replayID string, team *Team) error {
+	if team == nil || team.Lead == "" {
+		return nil
+	}
+
+	dir := filepath.Join(w.Dir, toID(team.Player))
+	file := filepath.Join(dir, replayID+".txt")
+	if w.Merged {
+		dir = w.Dir
+		file = filepath.Join(dir, toID(team.Player)+".txt")
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if w.Merged && w.written[file] {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	f, err := os.OpenFile(file, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if w.written == nil {
+		w.written = map[string]bool{}
+	}
+	w.written[file] = true
+
+	// The tier of the battle, a thread can mix them
+	format := w.Format
+	if team.Tier != "" {
+		format = toID(team.Tier)
+	}
+
+	_, err = f.WriteString("=== [" + format + "] " + replayID + " ===\n\n" + FormatPaste(team))
+	return err
+}
+
+// FormatPaste returns the team in showdown's import format, the fields and
+// moves which were not revealed nor known from the team are marked ???
+func FormatPaste(team *Team) string {
+	tera := team.Gen >= 9 // Tera types came with gen 9
+	nicks := make([]string, 0, len(team.Pokemons))
+	for nick := range team.Pokemons {
+		nicks = append(nicks, nick)
+	}
+	sort.Slice(nicks, func(i, j int) bool {
+		return team.Pokemons[nicks[i]].Name < team.Pokemons[nicks[j]].Name
+	})
+
+	res := ""
+	for _, nick := range nicks {
+		res += formatPokePaste(nick, team.Pokemons[nick], tera) + "\n"
+	}
+
+	return res
+}
+
+// Nickname (Species) @ Item
+// Ability: Ability
+// Tera Type: Type
+// EVs: 252 HP / 4 Def / 252 SpD
+// Bold Nature
+// IVs: 0 Atk
+// - Move
+func formatPokePaste(nick string, poke *Pokemon, tera bool) string {
+	res := poke.Name
+	if nick != poke.Name && !namesMatch(poke.Name, nick) {
+		res = nick + " (" + poke.Name + ")"
+	}
+	res += " @ " + orUnknown(poke.Item) + "\n"
+	res += "Ability: " + orUnknown(poke.Ability) + "\n"
+	if tera {
+		res += "Tera Type: " + orUnknown(poke.TeraType) + "\n"
+	}
+	if poke.EVs != "" {
+		res += "EVs: " + poke.EVs + "\n"
+	}
+	if poke.Nature != "" {
+		res += poke.Nature + " Nature\n"
+	}
+	if poke.IVs != "" {
+		res += "IVs: " + poke.IVs + "\n"
+	}
+
+	for _, move := range poke.Moves {
+		res += "- " + orUnknown(move) + "\n"
+	}
+
+	return res
+}
+
+func orUnknown(field string) string {
+	if strings.TrimSpace(field) == "" {
+		return unknownField
+	}
+
+	return field
+}
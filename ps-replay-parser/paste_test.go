@@ -0,0 +1,107 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatPokePaste(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	// The nickname is kept, the fields which were not revealed are unknown
+	want := `Bird (Pelipper) @ ???
+Ability: Drizzle
+- ???
+- ???
+- ???
+- ???
+`
+	if got := formatPokePaste("Bird", teams["p1"].Pokemons["Bird"], false); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	want = `Ferrothorn @ ???
+Ability: ???
+Tera Type: ???
+- Spikes
+- Leech Seed
+- ???
+- ???
+`
+	if got := formatPokePaste("Ferrothorn", teams["p1"].Pokemons["Ferrothorn"], true); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatPasteServerLog(t *testing.T) {
+	b, err := ioutil.ReadFile(serverLogFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	teams, err := ParsePokemonsFromServerLog(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `Ferrothorn @ Leftovers
+Ability: Iron Barbs
+EVs: 252 HP / 252 Def / 4 SpD
+Bold Nature
+IVs: 0 Atk
+`
+	paste := FormatPaste(teams["p1"])
+	if !strings.Contains(paste, want) || strings.Contains(paste, "Tera Type") {
+		t.Errorf("got\n%s", paste)
+	}
+}
+
+func TestFormatPasteTera(t *testing.T) {
+	teams := parseFixture(t, "gen9vgc2024regg-1.log")
+
+	want := `Flutter Mane @ Booster Energy
+Ability: Protosynthesis
+Tera Type: Fairy
+EVs: 4 HP / 252 SpA / 252 Spe
+Timid Nature
+- Moonblast
+`
+	if paste := FormatPaste(teams["p1"]); !strings.Contains(paste, want) {
+		t.Errorf("got\n%s", paste)
+	}
+}
+
+func TestPasteWriter(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+	dir := t.TempDir()
+
+	w := &PasteWriter{Dir: dir, Format: "gen8ou", Merged: true}
+	for _, id := range []string{"gen8ou-1", "gen8ou-2"} {
+		if err := w.Write(id, teams["p1"]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "alice.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	paste := string(b)
+	if !strings.HasPrefix(paste, "=== [gen8ou] gen8ou-1 ===\n\n") || !strings.Contains(paste, "=== [gen8ou] gen8ou-2 ===") {
+		t.Errorf("got\n%s", paste)
+	}
+
+	// A new run overwrites the merged file
+	w = &PasteWriter{Dir: dir, Format: "gen8ou", Merged: true}
+	if err := w.Write("gen8ou-3", teams["p1"]); err != nil {
+		t.Fatal(err)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(dir, "alice.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(b), "===") != 2 {
+		t.Errorf("got\n%s", b)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/nailec/ps-usage-stats/pokedex"
+)
+
+// Replay is a parsed replay, Teams is nil if it could not be parsed
+type Replay struct {
+	Path  string
+	Teams []*Team // p1 then p2
+	Errs  []*ParseError
+}
+
+// ParseReplays fetches, parses and finds the types of the teams of the
+// replays with the given number of workers, with the data of the generation
+// of the format unless the log tells it. The replays are sent on the
+// returned channel in input order as soon as they are ready
+func ParseReplays(paths []string, format string, isLogs, detectType bool, workers int) <-chan *Replay {
+	if workers < 1 {
+		workers = 1
+	}
+
+	// A replay is sent on its slot when parsed, so the output can wait for
+	// the next one in order while the workers keep going
+	slots := make([]chan *Replay, len(paths))
+	for i := range slots {
+		slots[i] = make(chan *Replay, 1)
+	}
+
+	jobs := make(chan int)
+	go func() {
+		for i := range paths {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				slots[i] <- parseReplay(paths[i], format, isLogs, detectType)
+			}
+		}()
+	}
+
+	replays := make(chan *Replay)
+	go func() {
+		for _, slot := range slots {
+			replays <- <-slot
+		}
+		close(replays)
+	}()
+
+	return replays
+}
+
+// parseReplay parses the replay and finds the types of its teams, a panic
+// skips the replay with a parse error instead of ending the run
+func parseReplay(path, format string, isLogs, detectType bool) (replay *Replay) {
+	replay = &Replay{Path: path}
+	defer func() {
+		if r := recover(); r != nil {
+			replay.Teams = nil
+			replay.Errs = append(replay.Errs, &ParseError{
+				ReplayID: replayID(path),
+				Reason:   "parse",
+				Err:      fmt.Errorf("%v", r),
+			})
+		}
+	}()
+
+	teams, err := parsePath(path, isLogs)
+	if err != nil {
+		replay.Errs = append(replay.Errs, err)
+		return replay
+	}
+
+	for _, p := range []string{"p1", "p2"} {
+		if teams[p].Gen == 0 {
+			teams[p].Gen = pokedex.FormatGen(format)
+		}
+	}
+
+	tierErr := checkTier(teams["p1"].Metadata, format)
+	if tierErr != nil {
+		replay.Errs = append(replay.Errs, toParseError(tierErr, "", replayID(path)))
+		if tierErr.Reason == "gen" {
+			return replay
+		}
+	}
+
+	for _, p := range []string{"p1", "p2"} {
+		team := teams[p]
+		var profileErr error
+		team.Profile, profileErr = GetProfile(team.Pokemons, team.Gen)
+		if profileErr != nil {
+			replay.Errs = append(replay.Errs, toParseError(profileErr, "type", replayID(path)))
+		}
+		if detectType {
+			var typeErr error
+			team.Type, team.Types, typeErr = GetType(team.Pokemons, team.Gen)
+			if typeErr != nil {
+				replay.Errs = append(replay.Errs, toParseError(typeErr, "type", replayID(path)))
+			}
+		}
+		for _, warning := range team.warnings {
+			replay.Errs = append(replay.Errs, toParseError(warning, "", replayID(path)))
+		}
+		replay.Teams = append(replay.Teams, team)
+	}
+
+	return replay
+}
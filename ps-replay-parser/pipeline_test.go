@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestParseReplaysOrder(t *testing.T) {
+	paths := []string{
+		"testdata/gen8ou-1.log",
+		"testdata/missing.log",
+		"testdata/gen8ou-1.log",
+		"testdata/missing.log",
+	}
+
+	i := 0
+	for replay := range ParseReplays(paths, "gen8ou", true, false, 3) {
+		if replay.Path != paths[i] {
+			t.Errorf("replay %d: got %s, want %s", i, replay.Path, paths[i])
+		}
+
+		if i%2 == 0 {
+			if len(replay.Teams) != 2 || len(replay.Errs) != 0 {
+				t.Errorf("replay %d: got %d teams and errors %v, want 2 teams", i, len(replay.Teams), replay.Errs)
+			}
+		} else {
+			if replay.Teams != nil || len(replay.Errs) != 1 || replay.Errs[0].Reason != "read" {
+				t.Errorf("replay %d: got %d teams and errors %v, want a read error", i, len(replay.Teams), replay.Errs)
+			}
+		}
+		i++
+	}
+
+	if i != len(paths) {
+		t.Errorf("got %d replays, want %d", i, len(paths))
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// |player|p1|Alice|266|1520
+// returns the rating of the player before the battle, 0 if unrated
+func getPlayerRating(line string) int {
+	split := strings.Split(line, "|")
+	if len(split) < 6 {
+		return 0
+	}
+
+	rating, _ := strconv.Atoi(split[5])
+	return rating
+}
+
+// |raw|Alice's rating: 1520 &rarr; <strong>1535</strong><br />(+15 for winning)
+// returns player name, rating before and after the battle
+func getRatingChange(line string) (string, int, int) {
+	expected := regexp.MustCompile(`\|raw\|(.*)'s rating: (\d+) &rarr; <strong>(\d+)</strong>`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", 0, 0
+	}
+
+	before, _ := strconv.Atoi(res[2])
+	after, _ := strconv.Atoi(res[3])
+	return res[1], before, after
+}
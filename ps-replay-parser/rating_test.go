@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestRatings(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	tests := []struct {
+		pID           string
+		before, after int
+	}{
+		{"p1", 1520, 1535},
+		{"p2", 1480, 1465},
+	}
+	for _, tt := range tests {
+		team := teams[tt.pID]
+		if team.RatingBefore != tt.before || team.RatingAfter != tt.after {
+			t.Errorf("%s: got %d -> %d, want %d -> %d", tt.pID, team.RatingBefore, team.RatingAfter, tt.before, tt.after)
+		}
+	}
+}
+
+func TestGetPlayerRating(t *testing.T) {
+	tests := map[string]int{
+		"|player|p1|Alice|266|1520": 1520,
+		"|player|p1|Alice|266|":     0,
+		"|player|p1|Alice|266":      0,
+	}
+	for line, want := range tests {
+		if got := getPlayerRating(line); got != want {
+			t.Errorf("%q: got %d, want %d", line, got, want)
+		}
+	}
+}
+
+func TestGetRatingChange(t *testing.T) {
+	name, before, after := getRatingChange("|raw|Bob's rating: 1480 &rarr; <strong>1465</strong><br />(-15 for losing)")
+	if name != "Bob" || before != 1480 || after != 1465 {
+		t.Errorf("got %s %d -> %d, want Bob 1480 -> 1465", name, before, after)
+	}
+}
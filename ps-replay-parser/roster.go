@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nailec/ps-usage-stats/pokedex"
+)
+
+// The pokemons of a side are identified by their slot in team preview, or by
+// their order of appearance without preview. The nicknames, species and formes
+// seen in the battle are resolved to a slot, Team.Pokemons only holds the
+// pokemons seen by nickname until the end of the parsing
+
+// addSlot adds a pokemon of team preview
+func addSlot(team *Team, name string) *Pokemon {
+	poke := &Pokemon{
+		Name:  name,
+		Slot:  len(team.slots),
+		Moves: make([]string, 4),
+	}
+	team.slots = append(team.slots, poke)
+	return poke
+}
+
+// resolvePoke returns the pokemon with this nickname, the first time it is
+// seen it is bound to the slot of its species. ok is false if the species was
+// not in team preview, the pokemon then gets a new slot. The species is named
+// as in the dex of the battle
+func resolvePoke(team *Team, dex *pokedex.Dex, nick, species string) (poke *Pokemon, ok bool) {
+	if poke, ok := team.Pokemons[nick]; ok {
+		return poke, true
+	}
+
+	species = dex.Normalize(species)
+	poke = freeSlot(team, species)
+	ok = poke != nil || !team.previewed
+	if poke == nil {
+		poke = addSlot(team, species)
+	}
+
+	// The preview hides some formes (Silvally-*, Urshifu-*), the switch tells
+	if poke.Name != species && !strings.HasPrefix(poke.Name, species+"-") {
+		poke.Name = species
+	}
+	poke.hidden = false
+	poke.Nickname = nick
+	team.Pokemons[nick] = poke
+	return poke, ok
+}
+
+// freeSlot returns the first unseen pokemon of this species, or of another
+// forme of it
+func freeSlot(team *Team, species string) *Pokemon {
+	for _, poke := range team.slots {
+		if poke.Nickname == "" && poke.Name == species {
+			return poke
+		}
+	}
+
+	for _, poke := range team.slots {
+		if poke.Nickname == "" && namesMatch(poke.Name, species) {
+			return poke
+		}
+	}
+
+	return nil
+}
+
+// finishRoster adds the pokemons never seen in the battle to Team.Pokemons,
+// by species or by species and slot if a nickname took it
+func finishRoster(team *Team) {
+	for _, poke := range team.slots {
+		if poke.Nickname != "" {
+			continue
+		}
+
+		key := poke.Name
+		if _, ok := team.Pokemons[key]; ok {
+			key += " (" + strconv.Itoa(poke.Slot+1) + ")"
+		}
+		team.Pokemons[key] = poke
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestRoster(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-3.log")
+	p1 := teams["p1"]
+
+	// A Pelipper nicknamed Swampert does not take the slot of the Swampert
+	tests := []struct {
+		nick, name string
+		slot       int
+	}{
+		{"Swampert", "Pelipper", 0},
+		{"Bob", "Swampert", 1},
+		{"Typey", "Silvally-Fire", 2},
+	}
+	for _, tt := range tests {
+		poke := p1.Pokemons[tt.nick]
+		if poke == nil || poke.Name != tt.name || poke.Slot != tt.slot {
+			t.Errorf("%s: got %+v, want %s in slot %d", tt.nick, poke, tt.name, tt.slot)
+		}
+	}
+	if len(p1.Pokemons) != 3 {
+		t.Errorf("got %d pokemons, want 3", len(p1.Pokemons))
+	}
+}
+
+func TestRosterWithoutPreview(t *testing.T) {
+	teams := parseFixture(t, "gen4ou-4.log")
+
+	if azelf, jirachi := teams["p1"].Pokemons["Azelf"], teams["p1"].Pokemons["Jirachi"]; azelf.Slot != 0 || jirachi.Slot != 1 {
+		t.Errorf("got Azelf in slot %d, Jirachi in slot %d, want 0 and 1", azelf.Slot, jirachi.Slot)
+	}
+	for _, id := range []string{"p1", "p2"} {
+		if len(teams[id].warnings) != 0 {
+			t.Errorf("%s: got warnings %v", id, teams[id].warnings)
+		}
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	replay := <-ParseReplays([]string{"testdata/gen8ou-3.log"}, "gen8ou", true, false, 1)
+	if len(replay.Teams) != 2 {
+		t.Fatalf("got %d teams, want 2", len(replay.Teams))
+	}
+
+	want := []struct {
+		reason string
+		line   int
+	}{
+		{"moveset", 24},
+		{"identity", 13},
+	}
+	if len(replay.Errs) != len(want) {
+		t.Fatalf("got %v, want %d warnings", replay.Errs, len(want))
+	}
+	for i, w := range want {
+		err := replay.Errs[i]
+		if err.Reason != w.reason || err.Line != w.line || err.ReplayID != "gen8ou-3" {
+			t.Errorf("warning %d: got %s line %d of %s, want %s line %d", i, err.Reason, err.Line, err.ReplayID, w.reason, w.line)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// serverLog is a battle log written by a showdown server in
+// logs/YYYY-MM/format/YYYY-MM-DD/*.log.json
+type serverLog struct {
+	Winner    string        `json:"winner"`
+	P1        string        `json:"p1"`
+	P2        string        `json:"p2"`
+	P1Team    []*PokemonSet `json:"p1team"`
+	P2Team    []*PokemonSet `json:"p2team"`
+	P1Rating  *serverRating `json:"p1rating"`
+	P2Rating  *serverRating `json:"p2rating"`
+	Log       []string      `json:"log"`
+	Format    string        `json:"format"`
+	Timestamp string        `json:"timestamp"`
+}
+
+type serverRating struct {
+	Elo    float64 `json:"elo"`
+	OldElo float64 `json:"oldelo"`
+}
+
+// ParsePokemonsFromServerLog returns the teams of a server log with their
+// full sets, the fields not revealed in the battle are marked as known
+func ParsePokemonsFromServerLog(content []byte) (map[string]*Team, error) {
+	var sl serverLog
+	err := json.Unmarshal(content, &sl)
+	if err != nil {
+		return nil, &ParseError{
+			Reason: "read",
+			Err:    errors.Wrap(err, "could not unmarshal server log"),
+		}
+	}
+
+	teams, err := ParsePokemonsFromHtml(spectatorLog(sl.Log))
+	if err != nil {
+		return nil, err
+	}
+
+	MergeSets(teams["p1"], sl.P1Team)
+	MergeSets(teams["p2"], sl.P2Team)
+	mergeRating(teams["p1"], sl.P1Rating)
+	mergeRating(teams["p2"], sl.P2Rating)
+
+	return teams, nil
+}
+
+// spectatorLog returns the log as seen by spectators: after |split|p1 comes
+// the line for p1 with exact hp then the one for everyone else
+func spectatorLog(lines []string) string {
+	res := make([]string, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		if strings.HasPrefix(lines[i], "|split|") {
+			i++ // skip the secret line
+			continue
+		}
+		res = append(res, lines[i])
+	}
+
+	return strings.Join(res, "\n")
+}
+
+func mergeRating(team *Team, rating *serverRating) {
+	if rating == nil {
+		return
+	}
+
+	if team.RatingBefore == 0 && rating.OldElo != 0 {
+		team.RatingBefore = int(rating.OldElo)
+	}
+
+	if team.RatingAfter == 0 {
+		team.RatingAfter = int(rating.Elo)
+	}
+}
+
+// GetPathsFromDir returns the replay files in the directory and its
+// subdirectories. The server logs are in logs/YYYY-MM/format/YYYY-MM-DD/, only
+// the ones of the format are kept. The error report and the pastes written by
+// a previous run are not replays
+func GetPathsFromDir(dir, format string) ([]string, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == pasteDir && path != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.Name() == errorReportFile {
+			return nil
+		}
+
+		if strings.HasSuffix(path, ".log.json") && isDate(filepath.Base(filepath.Dir(path))) &&
+			filepath.Base(filepath.Dir(filepath.Dir(path))) != format {
+			return nil
+		}
+
+		paths = append(paths, path)
+		return nil
+	})
+
+	return paths, err
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
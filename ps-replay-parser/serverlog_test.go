@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const serverLogFixture = "testdata/logs/2026-10/gen8ou/2026-10-19/gen8ou-1.log.json"
+
+func TestParseServerLog(t *testing.T) {
+	b, err := ioutil.ReadFile(serverLogFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	teams, err := ParsePokemonsFromServerLog(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The secret lines of |split| are skipped, the hp are in percent
+	ferro := teams["p1"].Pokemons["Ferrothorn"]
+	if ferro.HazardDamage != 3 {
+		t.Errorf("Ferrothorn hazard damage: got %d, want 3", ferro.HazardDamage)
+	}
+
+	if ferro.Item != "Leftovers" || ferro.Ability != "Iron Barbs" || ferro.Nature != "Bold" ||
+		ferro.EVs != "252 HP / 252 Def / 4 SpD" || ferro.IVs != "0 Atk" {
+		t.Errorf("Ferrothorn set: got %s @ %s %s %s %q", ferro.Ability, ferro.Item, ferro.Nature, ferro.EVs, ferro.IVs)
+	}
+	known := []string{"item", "ability", "nature", "evs", "ivs", "Knock Off", "Power Whip"}
+	if got := KnownFields(ferro); !reflect.DeepEqual(got, known) {
+		t.Errorf("Ferrothorn known fields: got %q, want %q", got, known)
+	}
+	if ferro.Origins["Spikes"] != Revealed || ferro.Origins["Leech Seed"] != Revealed {
+		t.Errorf("Ferrothorn revealed moves: got %v", ferro.Origins)
+	}
+
+	// The ability revealed in the battle is kept as revealed
+	lando := teams["p2"].Pokemons["Landorus"]
+	if lando.Ability != "Intimidate" || lando.Origins["ability"] != Revealed {
+		t.Errorf("Landorus ability: got %s %s", lando.Ability, lando.Origins["ability"])
+	}
+}
+
+func TestMergeRating(t *testing.T) {
+	team := &Team{}
+	mergeRating(team, &serverRating{Elo: 1535.4, OldElo: 1520.1})
+	if team.RatingBefore != 1520 || team.RatingAfter != 1535 {
+		t.Errorf("got %d -> %d, want 1520 -> 1535", team.RatingBefore, team.RatingAfter)
+	}
+
+	// The ratings of the log are kept
+	team = &Team{RatingBefore: 1500, RatingAfter: 1510}
+	mergeRating(team, &serverRating{Elo: 1535.4, OldElo: 1520.1})
+	if team.RatingBefore != 1500 || team.RatingAfter != 1510 {
+		t.Errorf("got %d -> %d, want 1500 -> 1510", team.RatingBefore, team.RatingAfter)
+	}
+}
+
+func TestSpectatorLog(t *testing.T) {
+	lines := []string{
+		"|split|p1",
+		"|switch|p1a: Bird|Pelipper, F|100/341",
+		"|switch|p1a: Bird|Pelipper, F|100/100",
+		"|turn|1",
+	}
+	want := "|switch|p1a: Bird|Pelipper, F|100/100\n|turn|1"
+	if got := spectatorLog(lines); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGetPathsFromDir(t *testing.T) {
+	paths, err := GetPathsFromDir("testdata", "gen8ou")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The server logs of the other formats are skipped
+	if !stringInSlice(serverLogFixture, paths) || !stringInSlice("testdata/gen8ou-1.log", paths) {
+		t.Errorf("got %q, want the gen8ou logs", paths)
+	}
+	for _, path := range paths {
+		if strings.Contains(path, "gen8uu") {
+			t.Errorf("got %q, want no gen8uu server log", path)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StatusEvent is a status received by a pokemon of the team
+type StatusEvent struct {
+	Status    string `json:"status"`    // brn, par, psn, tox, slp, frz
+	Target    string `json:"target"`    // Name of the pokemon who received it
+	Inflicter string `json:"inflicter"` // Name of the pokemon who inflicted it, "" if unknown
+	Cause     string `json:"cause"`     // Move, ability or item
+	TurnStart int    `json:"turn_start"`
+	TurnEnd   int    `json:"turn_end"`
+}
+
+// TurnsActive returns for how many turns the status persisted
+func (s *StatusEvent) TurnsActive() int {
+	return s.TurnEnd - s.TurnStart
+}
+
+// selfCauses are the statuses a pokemon inflicts to itself
+var selfCauses = []string{"Rest", "Flame Orb", "Toxic Orb"}
+
+// inflictStatus records the status on the pokemon of the target team,
+// inflicter is the pokemon of the team `by`, nil if unknown or self inflicted
+func inflictStatus(target *Team, targetNick string, by *Team, inflicterNick, status, cause string, turn int) {
+	poke, ok := target.Pokemons[targetNick]
+	if !ok {
+		return
+	}
+
+	event := &StatusEvent{
+		Status:    status,
+		Target:    poke.Name,
+		Cause:     cause,
+		TurnStart: turn,
+	}
+
+	if by != nil {
+		if inflicter, ok := by.Pokemons[inflicterNick]; ok {
+			event.Inflicter = inflicter.Name
+			addCount(&inflicter.StatusInflicted, status)
+		}
+	}
+
+	cureStatus(poke, turn)
+	addCount(&poke.StatusReceived, status)
+	poke.status = event
+	target.Statuses = append(target.Statuses, event)
+}
+
+// cureStatus ends the current status of the pokemon if any
+func cureStatus(poke *Pokemon, turn int) {
+	if poke == nil || poke.status == nil {
+		return
+	}
+
+	poke.status.TurnEnd = turn
+	poke.status = nil
+}
+
+func addCount(counts *map[string]int, key string) {
+	if *counts == nil {
+		*counts = map[string]int{}
+	}
+
+	(*counts)[key]++
+}
+
+// formatCounts returns the counts sorted by key: "brn:1,par:2"
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	res := make([]string, len(keys))
+	for i, key := range keys {
+		res[i] = key + ":" + strconv.Itoa(counts[key])
+	}
+
+	return strings.Join(res, ",")
+}
+
+// |-status|p2a: Heatran|brn|[from] ability: Flame Body|[of] p1a: Volcarona
+// returns player, nickname, status, source, source's player and nickname
+func getStatusInfo(line string) (string, string, string, string, string, string) {
+	expected := regexp.MustCompile(`\|-status\|(p(1|2))[a-d]: ([^\|]*)\|([^\|]*)(\|\[from\] (move: |ability: |item: )?([^\|]*))?(\|\[of\] (p(1|2))[a-d]: ([^\|]*))?`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", "", "", "", "", ""
+	}
+	return res[1], res[3], res[4], res[7], res[9], res[11]
+}
+
+// |-curestatus|p1a: Ferrothorn|brn|[msg]
+// |-curestatus|p1: Ferrothorn|brn|[msg] when it is not active
+// returns player and nickname
+func getCureStatusInfo(line string) (string, string) {
+	expected := regexp.MustCompile(`\|-curestatus\|(p(1|2))[a-d]?: ([^\|]*)\|`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", ""
+	}
+	return res[1], res[3]
+}
+
+// |-cureteam|p1a: Clefable|[from] move: Aromatherapy
+// returns player
+func getCureTeamInfo(line string) string {
+	expected := regexp.MustCompile(`\|-cureteam\|(p(1|2))[a-d]?: `)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return ""
+	}
+	return res[1]
+}
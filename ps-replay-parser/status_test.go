@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestStatuses(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	statuses := teams["p1"].Statuses
+	if len(statuses) != 1 {
+		t.Fatalf("got %d statuses, want 1", len(statuses))
+	}
+	want := StatusEvent{Status: "brn", Target: "Ferrothorn", Inflicter: "Heatran", Cause: "Will-O-Wisp",
+		TurnStart: 4, TurnEnd: 9}
+	if *statuses[0] != want {
+		t.Errorf("got %+v, want %+v", *statuses[0], want)
+	}
+
+	if got := teams["p2"].Pokemons["Heatran"].StatusInflicted["brn"]; got != 1 {
+		t.Errorf("Heatran inflicted: got %d brn, want 1", got)
+	}
+	if got := formatCounts(teams["p1"].Pokemons["Ferrothorn"].StatusReceived); got != "brn:1" {
+		t.Errorf("Ferrothorn received: got %q, want brn:1", got)
+	}
+}
+
+func TestGetStatusInfo(t *testing.T) {
+	pID, nick, status, cause, byID, byNick := getStatusInfo("|-status|p2a: Heatran|brn|[from] ability: Flame Body|[of] p1a: Volcarona")
+	if pID != "p2" || nick != "Heatran" || status != "brn" || cause != "Flame Body" || byID != "p1" || byNick != "Volcarona" {
+		t.Errorf("got %s %s %s %s %s %s", pID, nick, status, cause, byID, byNick)
+	}
+}
+
+func TestFormatCounts(t *testing.T) {
+	got := formatCounts(map[string]int{"par": 2, "brn": 1})
+	if got != "brn:1,par:2" {
+		t.Errorf("got %q, want brn:1,par:2", got)
+	}
+}
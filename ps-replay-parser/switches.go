@@ -0,0 +1,213 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Switch is a voluntary switch made by a team
+type Switch struct {
+	Turn     int    `json:"turn"`
+	Out      string `json:"out"`      // Name of the pokemon who left the field
+	In       string `json:"in"`       // Name of the pokemon who came in
+	Opponent string `json:"opponent"` // Name of the opposing active pokemon, the one facing it in doubles
+	OppMove  string `json:"opp_move"` // Move used by the opponent after the switch, "" if none
+	Pivot    string `json:"pivot"`    // Move the pokemon switched out with, "" for a hard switch
+}
+
+// Pivoting counts how the team brought its pokemons in and out
+type Pivoting struct {
+	HardSwitches   int `json:"hard_switches"`   // Voluntary switches chosen at the start of a turn
+	PivotSwitches  int `json:"pivot_switches"`  // Switches made with a pivot move
+	PivotMoves     int `json:"pivot_moves"`     // Pivot moves used, switching out or not
+	Drags          int `json:"drags"`           // Opposing pokemons forced out (Roar, Dragon Tail, ...)
+	Dragged        int `json:"dragged"`         // Pokemons of the team forced out
+	DoubleSwitches int `json:"double_switches"` // Turns both sides hard switched in
+}
+
+// pivotMoves switch the user out after use
+var pivotMoves = []string{
+	"U-turn", "Volt Switch", "Flip Turn", "Parting Shot", "Teleport",
+	"Baton Pass", "Shed Tail", "Chilly Reception",
+}
+
+// recordSwitch adds the switch to the team if the outgoing pokemon could
+// still fight, switches after a KO are not voluntary
+func recordSwitch(team, opp *Team, outNick, inNick, oppNick, pivot string, turn int) {
+	out, ok := team.Pokemons[outNick]
+	if !ok || out.Deaths != 0 {
+		return
+	}
+
+	team.Switches = append(team.Switches, &Switch{
+		Turn:     turn,
+		Out:      out.Name,
+		In:       pokeName(team, inNick),
+		Opponent: pokeName(opp, oppNick),
+		Pivot:    pivot,
+	})
+}
+
+// |switch|p1a: Bird|Pelipper, F|100/100
+// returns the position on the field: p1a
+func getPosition(line string) string {
+	expected := regexp.MustCompile(`^\|[^\|]*\|(p(1|2)[a-d]): `)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return ""
+	}
+	return res[1]
+}
+
+// facing returns the nickname of the opposing pokemon in the mirrored
+// position (p2a for p1a), or of the other opposing one if it is empty
+func facing(active map[string]string, pos string) string {
+	if len(pos) < 3 {
+		return ""
+	}
+
+	opp := getOpp(pos[:2])
+	if nick, ok := active[opp+pos[2:]]; ok {
+		return nick
+	}
+
+	slots := make([]string, 0, 3)
+	for p := range active {
+		if strings.HasPrefix(p, opp) {
+			slots = append(slots, p)
+		}
+	}
+	if len(slots) == 0 {
+		return ""
+	}
+	sort.Strings(slots)
+
+	return active[slots[0]]
+}
+
+// countSwitches fills the switch counts of the pivoting of both teams from
+// their switches
+func countSwitches(team, opp *Team) {
+	hard := map[int]bool{} // Turns the opponent hard switched in
+	for _, s := range opp.Switches {
+		if s.Pivot == "" {
+			hard[s.Turn] = true
+		}
+	}
+
+	doubles := map[int]bool{}
+	for _, s := range team.Switches {
+		if s.Pivot != "" {
+			team.Pivoting.PivotSwitches++
+			continue
+		}
+
+		team.Pivoting.HardSwitches++
+		if hard[s.Turn] {
+			doubles[s.Turn] = true
+		}
+	}
+	team.Pivoting.DoubleSwitches = len(doubles)
+}
+
+// countTurnsActive counts a turn for the pokemons on the field at its start
+func countTurnsActive(teams map[string]*Team, active map[string]string, disguises map[string]*disguise) {
+	for pos, nick := range active {
+		pID := pos[:2]
+		poke, ok := teams[pID].Pokemons[nick]
+		if !ok {
+			continue
+		}
+
+		poke.TurnsActive++
+		if d, ok := disguises[pos]; ok && d.nick == nick {
+			d.turns++
+		}
+	}
+}
+
+// strength returns the hp left in the team in percent, the pokemons not seen
+// yet count as healthy
+func strength(team *Team) int {
+	size := team.Brought
+	if size == 0 {
+		size = len(team.Preview)
+	}
+	if size == 0 {
+		size = 6
+	}
+
+	res := 0
+	for _, poke := range team.Pokemons {
+		if poke.Deaths == 0 {
+			res += poke.hp
+		}
+	}
+	if unseen := size - len(team.Pokemons); unseen > 0 {
+		res += 100 * unseen
+	}
+
+	return res
+}
+
+// recordMomentum adds the difference of strength between the teams at the
+// end of the turn, in hp percent
+func recordMomentum(teams map[string]*Team) {
+	p1, p2 := strength(teams["p1"]), strength(teams["p2"])
+	teams["p1"].Momentum = append(teams["p1"].Momentum, p1-p2)
+	teams["p2"].Momentum = append(teams["p2"].Momentum, p2-p1)
+}
+
+// formatMomentum returns the momentum of each turn separated by commas
+func formatMomentum(momentum []int) string {
+	res := make([]string, len(momentum))
+	for i, m := range momentum {
+		res[i] = strconv.Itoa(m)
+	}
+
+	return strings.Join(res, ",")
+}
+
+// fillSwitchMove sets the move used by the opponent on the switches of this
+// turn made into it
+func fillSwitchMove(team *Team, oppName, move string, turn int) {
+	for i := len(team.Switches) - 1; i >= 0; i-- {
+		s := team.Switches[i]
+		if s.Turn != turn {
+			return
+		}
+
+		if s.Opponent == oppName && s.OppMove == "" {
+			s.OppMove = move
+		}
+	}
+}
+
+// GetSwitchIns returns how many times each pokemon switched into an opposing
+// pokemon, keyed by opponent and incoming pokemon
+func GetSwitchIns(teams []*Team) map[string]int {
+	switchIns := map[string]int{}
+	for _, team := range teams {
+		if team == nil {
+			continue
+		}
+
+		for _, s := range team.Switches {
+			switchIns[s.Opponent+"\t"+s.In]++
+		}
+	}
+
+	return switchIns
+}
+
+// pokeName returns the name of the pokemon with this nickname if known
+func pokeName(team *Team, nick string) string {
+	if poke, ok := team.Pokemons[nick]; ok {
+		return poke.Name
+	}
+
+	return nick
+}
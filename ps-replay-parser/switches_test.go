@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSwitches(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	// The switches after a KO are not voluntary
+	want := map[string][]string{
+		"p1": {
+			"1 Pelipper>Swampert vs Landorus-Therian (Stealth Rock)",
+			"3 Swampert>Ferrothorn vs Heatran (Toxic)",
+			"7 Ferrothorn>Zapdos vs Corviknight (Brave Bird)",
+		},
+		"p2": {
+			"2 Landorus-Therian>Heatran vs Swampert (Stealth Rock)",
+			"5 Heatran>Corviknight vs Ferrothorn (Spikes)",
+		},
+	}
+	for pID, switches := range want {
+		var got []string
+		for _, s := range teams[pID].Switches {
+			got = append(got, fmt.Sprintf("%d %s>%s vs %s (%s)", s.Turn, s.Out, s.In, s.Opponent, s.OppMove))
+		}
+		if !reflect.DeepEqual(got, switches) {
+			t.Errorf("%s: got %q, want %q", pID, got, switches)
+		}
+	}
+}
+
+func TestGetSwitchIns(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	switchIns := GetSwitchIns([]*Team{teams["p1"], nil, teams["p2"]})
+	if got := switchIns["Heatran\tFerrothorn"]; got != 1 {
+		t.Errorf("Ferrothorn into Heatran: got %d, want 1", got)
+	}
+	if len(switchIns) != 5 {
+		t.Errorf("got %d matchups, want 5", len(switchIns))
+	}
+}
+
+func TestPivoting(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	want := map[string]Pivoting{
+		"p1": {HardSwitches: 3},
+		"p2": {HardSwitches: 1, PivotSwitches: 1, PivotMoves: 1},
+	}
+	for id, p := range want {
+		if got := teams[id].Pivoting; got != p {
+			t.Errorf("%s: got %+v, want %+v", id, got, p)
+		}
+	}
+	if pivot := teams["p2"].Switches[0].Pivot; pivot != "U-turn" {
+		t.Errorf("got pivot %q, want U-turn", pivot)
+	}
+
+	// The leads then each turn, the unseen pokemons count as healthy
+	p1, p2 := teams["p1"].Momentum, teams["p2"].Momentum
+	if len(p1) != 10 || p1[0] != 0 || p1[2] != -10 {
+		t.Errorf("got %v", p1)
+	}
+	for i := range p1 {
+		if p1[i] != -p2[i] {
+			t.Errorf("turn %d: got %d and %d", i, p1[i], p2[i])
+		}
+	}
+}
+
+func TestDragsAndDoubleSwitches(t *testing.T) {
+	log := `|player|p1|Alice|1|
+|player|p2|Bob|2|
+|poke|p1|Skarmory, F|
+|poke|p1|Blissey, F|
+|poke|p2|Landorus-Therian, M|
+|poke|p2|Heatran, M|
+|teampreview
+|start
+|switch|p1a: Skarmory|Skarmory, F|100/100
+|switch|p2a: Landorus|Landorus-Therian, M|100/100
+|turn|1
+|switch|p1a: Blissey|Blissey, F|100/100
+|switch|p2a: Heatran|Heatran, M|100/100
+|turn|2
+|move|p1a: Blissey|Teleport|p1a: Blissey
+|-fail|p1a: Blissey
+|move|p2a: Heatran|Roar|p1a: Blissey
+|drag|p1a: Skarmory|Skarmory, F|100/100
+|turn|3
+|switch|p1a: Blissey|Blissey, F|100/100
+|move|p2a: Heatran|Magma Storm|p1a: Blissey
+|-damage|p1a: Blissey|80/100
+|win|Alice
+`
+	teams := parseLog(t, log)
+
+	// The failed Teleport is not followed by its switch, the next one is hard
+	want := map[string]Pivoting{
+		"p1": {HardSwitches: 2, PivotMoves: 1, Dragged: 1, DoubleSwitches: 1},
+		"p2": {HardSwitches: 1, Drags: 1, DoubleSwitches: 1},
+	}
+	for id, p := range want {
+		if got := teams[id].Pivoting; got != p {
+			t.Errorf("%s: got %+v, want %+v", id, got, p)
+		}
+	}
+	if got := teams["p1"].Momentum; len(got) != 4 || got[3] != -20 {
+		t.Errorf("got momentum %v", got)
+	}
+}
+
+func TestFormatMomentum(t *testing.T) {
+	if got := formatMomentum([]int{0, -10, 25}); got != "0,-10,25" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestTurnsActive(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	// Zapdos came in and fainted during turn 7, Pelipper led and never moved
+	tests := []struct {
+		pID, nick    string
+		turns, moves int
+	}{
+		{"p1", "Bird", 1, 0},
+		{"p1", "Ferrothorn", 4, 3},
+		{"p1", "Zapdos", 0, 0},
+		{"p1", "Toxapex", 0, 0},
+		{"p2", "Corviknight", 3, 2},
+	}
+	for _, tt := range tests {
+		poke := teams[tt.pID].Pokemons[tt.nick]
+		if poke.TurnsActive != tt.turns || poke.MovesUsed != tt.moves {
+			t.Errorf("%s: got %d turns and %d moves, want %d and %d", tt.nick, poke.TurnsActive, poke.MovesUsed, tt.turns, tt.moves)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Origins of the fields of a pokemon
+const (
+	Revealed = "revealed" // Seen in the battle
+	Known    = "known"    // Only known from the team (server logs, team sheets)
+)
+
+// PokemonSet is a full set as written by showdown in its logs and team sheets
+type PokemonSet struct {
+	Name     string         `json:"name"` // Nickname
+	Species  string         `json:"species"`
+	Item     string         `json:"item"`
+	Ability  string         `json:"ability"`
+	Moves    []string       `json:"moves"`
+	Nature   string         `json:"nature"`
+	Gender   string         `json:"gender"`
+	EVs      map[string]int `json:"evs"`
+	IVs      map[string]int `json:"ivs"`
+	Level    int            `json:"level"`
+	TeraType string         `json:"teraType"`
+}
+
+// MergeSets completes the team with the full sets, the revealed fields are
+// kept and the others are marked as known. The species are named as in the
+// generation of the team's metadata
+func MergeSets(team *Team, sets []*PokemonSet) {
+	dex := team.Metadata.dex()
+	for _, set := range sets {
+		species := dex.Normalize(set.Species)
+		nick := set.Name
+		if nick == "" {
+			nick = species
+		}
+
+		poke := findPokemon(team, nick, species)
+		if poke == nil {
+			poke = addSlot(team, species)
+			poke.Nickname = set.Name
+			team.Pokemons[nick] = poke
+		}
+
+		// The sheet tells the formes hidden by the preview (Urshifu-*), they
+		// are the base species until then
+		if poke.Name != species && poke.Name == dex.BaseSpecies(species) {
+			poke.Name = species
+		}
+		poke.hidden = false
+
+		mergeSet(poke, set)
+	}
+}
+
+// findPokemon returns the pokemon by nickname or by species if it has not
+// been seen yet
+func findPokemon(team *Team, nick, species string) *Pokemon {
+	if poke, ok := team.Pokemons[nick]; ok {
+		return poke
+	}
+
+	for _, poke := range team.Pokemons {
+		if namesMatch(poke.Name, species) {
+			return poke
+		}
+	}
+
+	return nil
+}
+
+func mergeSet(poke *Pokemon, set *PokemonSet) {
+	poke.Item = mergeField(poke, "item", poke.Item, set.Item)
+	poke.Ability = mergeField(poke, "ability", poke.Ability, set.Ability)
+	poke.TeraType = mergeField(poke, "tera", poke.TeraType, set.TeraType)
+	poke.Nature = mergeField(poke, "nature", poke.Nature, set.Nature)
+	poke.EVs = mergeField(poke, "evs", poke.EVs, formatStats(set.EVs, 0))
+	poke.IVs = mergeField(poke, "ivs", poke.IVs, formatStats(set.IVs, 31))
+
+	revealed := map[string]bool{} // by ID, the names of packed teams are not exact
+	for _, move := range poke.Moves {
+		if move != "" {
+			setOrigin(poke, move, Revealed)
+			revealed[toID(move)] = true
+		}
+	}
+
+	for _, move := range set.Moves {
+		if move == "" || revealed[toID(move)] {
+			continue
+		}
+		addMove(poke.Moves, move)
+		setOrigin(poke, move, Known)
+	}
+}
+
+// mergeField returns the revealed value if any or the known one
+func mergeField(poke *Pokemon, field, revealed, known string) string {
+	if revealed != "" {
+		setOrigin(poke, field, Revealed)
+		return revealed
+	}
+
+	if known != "" {
+		setOrigin(poke, field, Known)
+	}
+
+	return known
+}
+
+func setOrigin(poke *Pokemon, field, origin string) {
+	if poke.Origins == nil {
+		poke.Origins = map[string]string{}
+	}
+
+	poke.Origins[field] = origin
+}
+
+// KnownFields returns the fields and moves of the pokemon which were not
+// revealed in the battle
+func KnownFields(poke *Pokemon) []string {
+	var fields []string
+	for _, field := range []string{"item", "ability", "tera", "nature", "evs", "ivs"} {
+		if poke.Origins[field] == Known {
+			fields = append(fields, field)
+		}
+	}
+
+	for _, move := range poke.Moves {
+		if poke.Origins[move] == Known {
+			fields = append(fields, move)
+		}
+	}
+
+	return fields
+}
+
+var statNames = []string{"hp", "atk", "def", "spa", "spd", "spe"}
+var statLabels = map[string]string{
+	"hp":  "HP",
+	"atk": "Atk",
+	"def": "Def",
+	"spa": "SpA",
+	"spd": "SpD",
+	"spe": "Spe",
+}
+
+// formatStats returns the stats which are not the default value as in the
+// teambuilder: "252 HP / 4 Def / 252 SpD"
+func formatStats(stats map[string]int, def int) string {
+	var res []string
+	for _, stat := range statNames {
+		if v, ok := stats[stat]; ok && v != def {
+			res = append(res, strconv.Itoa(v)+" "+statLabels[stat])
+		}
+	}
+
+	return strings.Join(res, " / ")
+}
+
+// |-ability|p2a: Landorus|Intimidate|boost
+// |-weather|RainDance|[from] ability: Drizzle|[of] p1a: Bird
+// returns player, nickname and ability, the pokemon is the [of] one if any
+func getAbility(line string) (string, string, string) {
+	if strings.HasPrefix(line, "|-ability|") {
+		expected := regexp.MustCompile(`\|-ability\|(p(1|2))[a-d]: ([^\|]*)\|([^\|]*)(\|\[from\] ([^\|]*))?`)
+
+		res := expected.FindStringSubmatch(line)
+		if len(res) == 0 {
+			return "", "", ""
+		}
+		if res[6] == "ability: Trace" {
+			return res[1], res[3], "Trace"
+		}
+		if res[6] != "" { // Skill Swap, Role Play, ...
+			return "", "", ""
+		}
+		return res[1], res[3], res[4]
+	}
+
+	expected := regexp.MustCompile(`^\|[^\|]*\|(p(1|2))[a-d]: ([^\|]*)\|.*\[from\] ability: ([^\|]*)(\|\[of\] (p(1|2))[a-d]: ([^\|]*))?`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		// |-weather|RainDance|[from] ability: Drizzle|[of] p1a: Bird
+		expected = regexp.MustCompile(`\[from\] ability: ([^\|]*)\|\[of\] (p(1|2))[a-d]: ([^\|]*)`)
+		res = expected.FindStringSubmatch(line)
+		if len(res) == 0 {
+			return "", "", ""
+		}
+		return res[2], res[4], res[1]
+	}
+	if res[6] != "" {
+		return res[6], res[8], res[4]
+	}
+	return res[1], res[3], res[4]
+}
+
+// |-terastallize|p1a: Garganacl|Water
+// returns player, nickname and tera type
+func getTeraInfo(line string) (string, string, string) {
+	expected := regexp.MustCompile(`\|-terastallize\|(p(1|2))[a-d]: ([^\|]*)\|([^\|]*)`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", "", ""
+	}
+	return res[1], res[3], res[4]
+}
+
+// |showteam|p1|Flutter Mane||BoosterEnergy|Protosynthesis|Moonblast,ShadowBall,Protect,DazzlingGleam||||||50|,,,,,Fairy]...
+// returns player and the sets of the team
+func getShowTeamInfo(line string) (string, []*PokemonSet) {
+	split := strings.SplitN(line, "|", 4)
+	if len(split) < 4 {
+		return "", nil
+	}
+
+	return split[2], parsePackedTeam(split[3])
+}
+
+// parsePackedTeam returns the sets of a team in showdown's packed format:
+// name|species|item|ability|moves|nature|evs|gender|ivs|shiny|level|happiness,pokeball,hptype,gigantamax,dynamaxlevel,teratype
+// with the pokemons separated by "]". The item, ability and moves are packed
+// without spaces nor punctuation, see unpackName. The species is not packed
+func parsePackedTeam(packed string) []*PokemonSet {
+	var sets []*PokemonSet
+	for _, p := range strings.Split(packed, "]") {
+		fields := strings.Split(p, "|")
+		if len(fields) < 11 {
+			continue
+		}
+
+		set := &PokemonSet{
+			Name:    fields[0],
+			Species: fields[1],
+			Item:    unpackName(fields[2]),
+			Ability: unpackName(fields[3]),
+			Nature:  fields[5],
+			Gender:  fields[7],
+			EVs:     unpackStats(fields[6], 0),
+			IVs:     unpackStats(fields[8], 31),
+		}
+		if set.Species == "" {
+			set.Species = set.Name
+			set.Name = ""
+		}
+
+		for _, move := range strings.Split(fields[4], ",") {
+			if move != "" {
+				set.Moves = append(set.Moves, unpackName(move))
+			}
+		}
+
+		set.Level, _ = strconv.Atoi(fields[10])
+		if len(fields) > 11 {
+			misc := strings.Split(fields[11], ",")
+			if len(misc) > 5 {
+				set.TeraType = misc[5]
+			}
+		}
+
+		sets = append(sets, set)
+	}
+
+	return sets
+}
+
+// unpackStats returns the stats from "252,,4,,252,", empty ones are the default
+func unpackStats(packed string, def int) map[string]int {
+	if packed == "" {
+		return nil
+	}
+
+	stats := map[string]int{}
+	for i, v := range strings.Split(packed, ",") {
+		if i >= len(statNames) {
+			break
+		}
+
+		stats[statNames[i]] = def
+		if n, err := strconv.Atoi(v); err == nil {
+			stats[statNames[i]] = n
+		}
+	}
+
+	return stats
+}
+
+// toID returns the name in lower case without spaces nor punctuation
+func toID(name string) string {
+	res := ""
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			res += string(r)
+		}
+	}
+
+	return res
+}
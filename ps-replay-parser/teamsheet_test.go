@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatStats(t *testing.T) {
+	evs := map[string]int{"hp": 252, "atk": 0, "def": 4, "spa": 0, "spd": 252, "spe": 0}
+	if got := formatStats(evs, 0); got != "252 HP / 4 Def / 252 SpD" {
+		t.Errorf("evs: got %q", got)
+	}
+
+	ivs := map[string]int{"hp": 31, "atk": 0, "def": 31, "spa": 31, "spd": 31, "spe": 31}
+	if got := formatStats(ivs, 31); got != "0 Atk" {
+		t.Errorf("ivs: got %q", got)
+	}
+}
+
+func TestGetAbility(t *testing.T) {
+	tests := []struct {
+		line, pID, nick, ability string
+	}{
+		{"|-ability|p2a: Landorus|Intimidate|boost", "p2", "Landorus", "Intimidate"},
+		{"|-weather|RainDance|[from] ability: Drizzle|[of] p1a: Bird", "p1", "Bird", "Drizzle"},
+		{"|-damage|p2a: Landorus|80/100|[from] ability: Iron Barbs|[of] p1a: Ferrothorn", "p1", "Ferrothorn", "Iron Barbs"},
+		{"|-ability|p1a: Bird|Intimidate|[from] move: Skill Swap", "", "", ""},
+	}
+	for _, tt := range tests {
+		pID, nick, ability := getAbility(tt.line)
+		if pID != tt.pID || nick != tt.nick || ability != tt.ability {
+			t.Errorf("%q: got %q %q %q", tt.line, pID, nick, ability)
+		}
+	}
+}
+
+func TestShowTeam(t *testing.T) {
+	teams := parseFixture(t, "gen9vgc2024regg-1.log")
+
+	// Both sides of the doubles battle are followed
+	flutter := teams["p1"].Pokemons["Flutter Mane"]
+	caly := teams["p2"].Pokemons["Calyrex"]
+	if flutter.Kills != 1 || caly.Deaths != 1 {
+		t.Errorf("got Flutter Mane %d kills, Calyrex %d deaths, want 1 and 1", flutter.Kills, caly.Deaths)
+	}
+	if incin := teams["p2"].Pokemons["Incineroar"]; incin.hp != 60 {
+		t.Errorf("Incineroar hp: got %d, want 60", incin.hp)
+	}
+
+	if flutter.Item != "Booster Energy" || flutter.TeraType != "Fairy" || flutter.EVs != "4 HP / 252 SpA / 252 Spe" {
+		t.Errorf("Flutter Mane set: got %s %s %s", flutter.Item, flutter.TeraType, flutter.EVs)
+	}
+	if flutter.Origins["Moonblast"] != Revealed || flutter.Origins["Shadow Ball"] != Known {
+		t.Errorf("Flutter Mane moves: got %v", flutter.Origins)
+	}
+	if caly.Ability != "As One (Spectrier)" || caly.Moves[3] != "Psyshock" {
+		t.Errorf("Calyrex set: got %s %q", caly.Ability, caly.Moves)
+	}
+
+	// The pokemons brought but never sent are known from the sheet
+	farigiraf := teams["p2"].Pokemons["Farigiraf"]
+	if farigiraf.Item != "Electric Seed" || farigiraf.Origins["item"] != Known {
+		t.Errorf("Farigiraf item: got %s %s", farigiraf.Item, farigiraf.Origins["item"])
+	}
+}
+
+func TestParsePackedTeam(t *testing.T) {
+	sets := parsePackedTeam("Bird|Pelipper|DampRock|Drizzle|Hurricane,UTurn,Roost,WillOWisp|Bold|248,,252,,8,|F|,0,,,,||100|,,,,,Water]Ferrothorn||Leftovers|IronBarbs|Spikes||||||50|")
+	if len(sets) != 2 {
+		t.Fatalf("got %d sets, want 2", len(sets))
+	}
+
+	bird := sets[0]
+	if bird.Name != "Bird" || bird.Species != "Pelipper" || bird.Item != "Damp Rock" || bird.Level != 100 || bird.TeraType != "Water" {
+		t.Errorf("got %+v", *bird)
+	}
+	if got := formatStats(bird.IVs, 31); got != "0 Atk" {
+		t.Errorf("ivs: got %q", got)
+	}
+	if got := strings.Join(bird.Moves, ","); got != "Hurricane,U-turn,Roost,Will-O-Wisp" {
+		t.Errorf("moves: got %q", got)
+	}
+
+	if ferro := sets[1]; ferro.Name != "" || ferro.Species != "Ferrothorn" || ferro.Ability != "Iron Barbs" {
+		t.Errorf("got %+v", *ferro)
+	}
+}
+
+func TestUnpackName(t *testing.T) {
+	tests := map[string]string{
+		"ShadowBall":              "Shadow Ball",
+		"HeavyDutyBoots":          "Heavy-Duty Boots",
+		"KingsShield":             "King's Shield",
+		"AsOneSpectrier":          "As One (Spectrier)",
+		"Thunderbolt":             "Thunderbolt",
+		"Shadow Ball":             "Shadow Ball",
+		"10000000VoltThunderbolt": "10000000 Volt Thunderbolt",
+	}
+	for packed, want := range tests {
+		if got := unpackName(packed); got != want {
+			t.Errorf("%s: got %q, want %q", packed, got, want)
+		}
+	}
+}
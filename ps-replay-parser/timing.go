@@ -0,0 +1,98 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// Timing is the wall-clock time of the battle from its |t:| lines, in
+// seconds. The players choose at the same time, the time of a turn is only
+// given to a side when it alone had to pick a replacement after a KO
+type Timing struct {
+	Start      int64 `json:"start"`       // Unix time of the first |t:| line, 0 if the log has none
+	Duration   int   `json:"duration"`    // From the first to the last |t:| line
+	TurnTimes  []int `json:"turn_times"`  // By turn, 0 is team preview
+	SwitchTime int   `json:"switch_time"` // Spent by the player picking replacements after a KO
+	TimerLeft  int   `json:"timer_left"`  // At the last timer warning of the player, -1 if none
+}
+
+// battleClock adds up the |t:| lines of a battle
+type battleClock struct {
+	start, last int64
+	lastTurn    int
+	turns       []int
+	forced      map[string]bool // Sides which must pick a replacement
+	switchTimes map[string]int  // By player ID
+}
+
+func newBattleClock() *battleClock {
+	return &battleClock{
+		forced:      map[string]bool{},
+		switchTimes: map[string]int{},
+	}
+}
+
+// stamp adds the time since the last stamp to the turn, and to the sides who
+// had to pick a replacement if the turn did not change
+func (c *battleClock) stamp(ts int64, turn int) {
+	if c.start == 0 {
+		c.start = ts
+	} else {
+		elapsed := int(ts - c.last)
+		for len(c.turns) <= turn {
+			c.turns = append(c.turns, 0)
+		}
+		c.turns[turn] += elapsed
+
+		if turn == c.lastTurn {
+			for pID := range c.forced {
+				c.switchTimes[pID] += elapsed
+			}
+		}
+	}
+
+	c.forced = map[string]bool{}
+	c.last = ts
+	c.lastTurn = turn
+}
+
+// timing returns the timing of the battle for the player, but TimerLeft
+func (c *battleClock) timing(pID string) Timing {
+	t := Timing{
+		Start:      c.start,
+		Duration:   int(c.last - c.start),
+		TurnTimes:  c.turns,
+		SwitchTime: c.switchTimes[pID],
+	}
+
+	return t
+}
+
+// |t:|1618324440
+// returns the unix time, 0 if it cannot be read
+func getTimestamp(line string) int64 {
+	expected := regexp.MustCompile(`^\|t:\|(\d+)`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return 0
+	}
+
+	ts, _ := strconv.ParseInt(res[1], 10, 64)
+	return ts
+}
+
+// |inactive|Alice has 120 seconds left.
+// returns the player name and the seconds left, ok is false for the other
+// timer messages
+func getTimerLeft(line string) (string, int, bool) {
+	expected := regexp.MustCompile(`^\|inactive\|(.*) has (\d+) seconds left`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", 0, false
+	}
+
+	left, _ := strconv.Atoi(res[2])
+	return res[1], left, true
+}
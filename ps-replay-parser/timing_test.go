@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTiming(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	// Only p1 had to pick a replacement, after the KO of Zapdos
+	want := Timing{
+		Start:      1600000000,
+		Duration:   300,
+		TurnTimes:  []int{30, 15, 35, 20, 30, 30, 30, 50, 20, 40},
+		SwitchTime: 20,
+		TimerLeft:  -1,
+	}
+	if got := teams["p1"].Timing; !reflect.DeepEqual(got, want) {
+		t.Errorf("p1: got %+v, want %+v", got, want)
+	}
+	if got := teams["p2"].SwitchTime; got != 0 {
+		t.Errorf("p2: got a switch time of %d, want 0", got)
+	}
+}
+
+func TestTimerLeft(t *testing.T) {
+	log := readFixture(t, "gen8ou-1.log")
+	log = strings.Replace(log, "|turn|8\n", "|turn|8\n|inactive|Battle timer is ON: inactive players will automatically lose when time's up.\n|inactive|Bob has 120 seconds left.\n|inactive|Bob has 90 seconds left.\n", 1)
+	teams := parseLog(t, log)
+
+	if p1, p2 := teams["p1"].TimerLeft, teams["p2"].TimerLeft; p1 != -1 || p2 != 90 {
+		t.Errorf("got %d and %d, want -1 and 90", p1, p2)
+	}
+}
+
+func TestTimingWithoutTimestamps(t *testing.T) {
+	log := readFixture(t, "gen4ou-4.log")
+	teams := parseLog(t, log)
+
+	want := Timing{TimerLeft: -1}
+	if got := teams["p1"].Timing; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestGetTimestamp(t *testing.T) {
+	tests := map[string]int64{
+		"|t:|1618324440": 1618324440,
+		"|t:|":           0,
+		"|turn|1":        0,
+	}
+	for line, want := range tests {
+		if got := getTimestamp(line); got != want {
+			t.Errorf("%q: got %d, want %d", line, got, want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// FieldCondition is a weather or a terrain set by a team
+type FieldCondition struct {
+	Name      string `json:"name"`   // RainDance, Electric Terrain, ...
+	Setter    string `json:"setter"` // Name of the pokemon who set it
+	TurnStart int    `json:"turn_start"`
+	TurnEnd   int    `json:"turn_end"`
+	Kills     int    `json:"kills"`     // KOs scored by the setter's side while active
+	OppKills  int    `json:"opp_kills"` // KOs scored by the opponent while active
+}
+
+var weatherLabels = map[string]string{
+	"RainDance":     "Rain",
+	"PrimordialSea": "Rain",
+	"SunnyDay":      "Sun",
+	"DesolateLand":  "Sun",
+	"Sandstorm":     "Sand",
+	"Hail":          "Hail",
+	"Snow":          "Snow",
+	"DeltaStream":   "Wind",
+}
+
+var terrainLabels = map[string]string{
+	"Electric Terrain": "Electric",
+	"Grassy Terrain":   "Grassy",
+	"Misty Terrain":    "Misty",
+	"Psychic Terrain":  "Psychic",
+}
+
+// TurnsActive returns for how many turns the condition was active
+func (c *FieldCondition) TurnsActive() int {
+	return c.TurnEnd - c.TurnStart
+}
+
+// Benefited returns the side who scored the most KOs under the condition,
+// "setter", "opponent" or "" if even
+func (c *FieldCondition) Benefited() string {
+	if c.Kills > c.OppKills {
+		return "setter"
+	}
+
+	if c.OppKills > c.Kills {
+		return "opponent"
+	}
+
+	return ""
+}
+
+// startCondition adds a condition set by the pokemon to the team
+func startCondition(team *Team, pokeNick, name string, turn int) *FieldCondition {
+	setter := pokeNick
+	if poke, ok := team.Pokemons[pokeNick]; ok {
+		setter = poke.Name
+	}
+
+	c := &FieldCondition{
+		Name:      name,
+		Setter:    setter,
+		TurnStart: turn,
+	}
+	team.Conditions = append(team.Conditions, c)
+
+	return c
+}
+
+// endCondition ends the condition if any and returns nil
+func endCondition(c *FieldCondition, turn int) *FieldCondition {
+	if c != nil {
+		c.TurnEnd = turn
+	}
+
+	return nil
+}
+
+// addConditionKill counts a KO scored by the killer side under the condition
+// set by the side
+func addConditionKill(c *FieldCondition, side, killer string) {
+	if c == nil {
+		return
+	}
+
+	if side == killer {
+		c.Kills++
+	} else {
+		c.OppKills++
+	}
+}
+
+// GetArchetype returns the weather and terrain labels of the conditions set
+// by the team, "Rain", "Sun+Electric", ... or "" if none
+func GetArchetype(team *Team) string {
+	var weathers, terrains []string
+	for _, c := range team.Conditions {
+		if label, ok := weatherLabels[c.Name]; ok && !stringInSlice(label, weathers) {
+			weathers = append(weathers, label)
+		}
+		if label, ok := terrainLabels[c.Name]; ok && !stringInSlice(label, terrains) {
+			terrains = append(terrains, label)
+		}
+	}
+
+	return strings.Join(append(weathers, terrains...), "+")
+}
+
+// |-weather|RainDance|[from] ability: Drizzle|[of] p1a: Bird
+// returns weather, whether it is an upkeep, setter's player and nickname
+func getWeatherInfo(line string) (string, bool, string, string) {
+	expected := regexp.MustCompile(`\|-weather\|([^\|]*)(\|\[from\] [^\|]*)?(\|\[of\] (p(1|2))[a-d]: ([^\|]*))?(\|\[upkeep\])?`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", false, "", ""
+	}
+	return res[1], res[7] != "", res[4], res[6]
+}
+
+// |-fieldstart|move: Electric Terrain|[from] ability: Electric Surge|[of] p1a: Tapu Koko
+// returns condition, setter's player and nickname
+func getFieldStartInfo(line string) (string, string, string) {
+	expected := regexp.MustCompile(`\|-fieldstart\|(move: )?([^\|]*)(\|\[from\] [^\|]*)?(\|\[of\] (p(1|2))[a-d]: ([^\|]*))?`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return "", "", ""
+	}
+	return res[2], res[5], res[7]
+}
+
+// |-fieldend|move: Electric Terrain
+// returns condition
+func getFieldEndInfo(line string) string {
+	expected := regexp.MustCompile(`\|-fieldend\|(move: )?([^\|]*)`)
+
+	res := expected.FindStringSubmatch(line)
+	if len(res) == 0 {
+		return ""
+	}
+	return res[2]
+}
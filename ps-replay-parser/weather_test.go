@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestWeather(t *testing.T) {
+	teams := parseFixture(t, "gen8ou-1.log")
+
+	conditions := teams["p1"].Conditions
+	if len(conditions) != 1 {
+		t.Fatalf("got %d conditions, want 1", len(conditions))
+	}
+	want := FieldCondition{Name: "RainDance", Setter: "Pelipper", TurnStart: 0, TurnEnd: 5}
+	if *conditions[0] != want {
+		t.Errorf("got %+v, want %+v", *conditions[0], want)
+	}
+	if len(teams["p2"].Conditions) != 0 {
+		t.Errorf("p2: got %d conditions, want none", len(teams["p2"].Conditions))
+	}
+	if teams["p1"].Archetype != "Rain" {
+		t.Errorf("archetype: got %q, want Rain", teams["p1"].Archetype)
+	}
+}
+
+func TestGetArchetype(t *testing.T) {
+	team := &Team{Conditions: []*FieldCondition{
+		{Name: "SunnyDay"},
+		{Name: "Electric Terrain"},
+		{Name: "DesolateLand"},
+	}}
+	if got := GetArchetype(team); got != "Sun+Electric" {
+		t.Errorf("got %q, want Sun+Electric", got)
+	}
+}
+
+func TestConditionBenefited(t *testing.T) {
+	tests := []struct {
+		kills, oppKills int
+		want            string
+	}{
+		{2, 1, "setter"},
+		{0, 1, "opponent"},
+		{1, 1, ""},
+	}
+	for _, tt := range tests {
+		c := &FieldCondition{Kills: tt.kills, OppKills: tt.oppKills}
+		if got := c.Benefited(); got != tt.want {
+			t.Errorf("%d-%d: got %q, want %q", tt.kills, tt.oppKills, got, tt.want)
+		}
+	}
+}